@@ -5,10 +5,15 @@ package main
 import (
 	"bytes"
 	"encoding/gob"
+	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	bolt "go.etcd.io/bbolt"
 )
@@ -16,16 +21,27 @@ import (
 ////////////////////////////////////////////////////////////////////////////////
 
 const (
-	bucketName = "Aliases"
+	bucketName      = "Aliases"
+	wakesBucketName = "Wakes"
+
+	// tagsField is the MacIface field which holds an alias's comma separated
+	// list of tags.
+	tagsField = "tags"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
 
 // MacIface holds a MAC Address to wake up, along with an optionally specified
 // default interface to use when typically waking up said interface.
+//
+// Gob silently drops struct fields which the decoding binary does not know
+// about, so any per-alias data added from here on must be stored in `Fields`
+// rather than as new struct members. This way older binaries which rewrite an
+// entry carry the fields they do not understand along with it.
 type MacIface struct {
-	Mac   string
-	Iface string
+	Mac    string
+	Iface  string
+	Fields map[string]string
 }
 
 // DecodeToMacIface takes a byte buffer and converts decodes it using the gob
@@ -40,66 +56,280 @@ func DecodeToMacIface(buf *bytes.Buffer) (MacIface, error) {
 // EncodeFromMacIface takes a MAC and an Iface and encodes a gob with a MacIface
 // entry.
 func EncodeFromMacIface(mac, iface string) (*bytes.Buffer, error) {
+	return EncodeMacIface(MacIface{Mac: mac, Iface: iface})
+}
+
+// EncodeMacIface encodes a gob with the given MacIface entry.
+func EncodeMacIface(entry MacIface) (*bytes.Buffer, error) {
 	buf := bytes.NewBuffer(nil)
-	entry := MacIface{mac, iface}
 	err := gob.NewEncoder(buf).Encode(entry)
 	return buf, err
 }
 
+// normalizeMAC returns a canonical representation of a MAC address so that
+// `00-AA-..` and `00:aa:..` compare equal. Invalid MACs are returned as-is.
+func normalizeMAC(mac string) string {
+	if hw, err := net.ParseMAC(mac); err == nil {
+		return hw.String()
+	}
+	return mac
+}
+
+// Tags returns the tags of the entry.
+func (mi MacIface) Tags() []string {
+	if mi.Fields[tagsField] == "" {
+		return nil
+	}
+	return strings.Split(mi.Fields[tagsField], ",")
+}
+
+// addTags adds any of `tags` which the entry does not have yet.
+func addTags(mi *MacIface, tags ...string) {
+	current := mi.Tags()
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		found := tag == ""
+		for _, t := range current {
+			found = found || t == tag
+		}
+		if !found {
+			current = append(current, tag)
+		}
+	}
+	if len(current) == 0 {
+		return
+	}
+	if mi.Fields == nil {
+		mi.Fields = map[string]string{}
+	}
+	mi.Fields[tagsField] = strings.Join(current, ",")
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 
 // Aliases holds a pointer to a mutex which will be acquired and released as
 // transactions are carried out on the `db`.
 type Aliases struct {
-	mtx *sync.Mutex
-	db  *bolt.DB
+	mtx      *sync.Mutex
+	db       *bolt.DB
+	path     string
+	readOnly bool
+}
+
+// AliasOptions control how the alias db is opened.
+type AliasOptions struct {
+	// ReadOnly opens the db with a shared lock and rejects all mutations.
+	ReadOnly bool
+
+	// Timeout is how long to wait for another process to release the db. A
+	// zero timeout waits forever.
+	Timeout time.Duration
+}
+
+var errReadOnly = errors.New("alias db is opened read-only")
+
+// lockedDBError is returned when the alias db is held by another process for
+// longer than the lock timeout.
+type lockedDBError struct {
+	path, owner string
 }
 
-// LoadAliases fetches a boltDb entity at a given `dbpath`. The db just contains
-// a default bucket called `Aliases` which is where the alias entries are
-// stored.
+func (e *lockedDBError) Error() string {
+	return fmt.Sprintf("timed out opening %s: database locked by %s", e.path, e.owner)
+}
+
+// LoadAliases fetches a boltDb entity at a given `dbpath`. The db contains a
+// default bucket called `Aliases` which is where the alias entries are stored,
+// a `Wakes` bucket which tracks when each MAC was last woken, and a `Meta`
+// bucket which holds the schema version. Older dbs are migrated on load.
 func LoadAliases(dbpath string) (*Aliases, error) {
-	err := os.MkdirAll(filepath.Dir(dbpath), os.ModePerm)
-	if err != nil {
-		return nil, err
+	return LoadAliasesWithOptions(dbpath, AliasOptions{})
+}
+
+// LoadAliasesWithOptions is like LoadAliases, but allows the db to be opened
+// read-only and with a lock timeout.
+func LoadAliasesWithOptions(dbpath string, opts AliasOptions) (*Aliases, error) {
+	if !opts.ReadOnly {
+		err := os.MkdirAll(filepath.Dir(dbpath), os.ModePerm)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	db, err := bolt.Open(dbpath, 0660, nil)
+	db, err := openBolt(dbpath, &bolt.Options{
+		ReadOnly: opts.ReadOnly,
+		Timeout:  opts.Timeout,
+	})
+	if err == bolt.ErrTimeout {
+		return nil, &lockedDBError{dbpath, lockOwner(dbpath)}
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	if err := db.Update(func(tx *bolt.Tx) error {
-		if _, lerr := tx.CreateBucketIfNotExists([]byte(bucketName)); lerr != nil {
-			return lerr
+	if !opts.ReadOnly {
+		err := migrateSchema(db, dbpath)
+		if err == errSchemaTooNew {
+			// A newer binary owns this db. We can still read it, but must not
+			// write to it since we do not know what its layout means.
+			db.Close()
+			fmt.Fprintf(os.Stderr, "Warning: alias db %s was written by a newer version of wol, opening read-only\n", dbpath)
+			opts.ReadOnly = true
+			return LoadAliasesWithOptions(dbpath, opts)
 		}
-		return nil
-	}); err != nil {
-		return nil, err
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+
+		// Leave a note for anyone else waiting on the db lock. This is purely
+		// informational, so failing to write it is not fatal.
+		writeLockOwner(dbpath)
 	}
 
 	return &Aliases{
-		mtx: &sync.Mutex{},
-		db:  db,
+		mtx:      &sync.Mutex{},
+		db:       db,
+		path:     dbpath,
+		readOnly: opts.ReadOnly,
 	}, nil
 }
 
+// lockOwnerPath returns the path of the file which describes the process that
+// holds the write lock on the db at `dbpath`.
+func lockOwnerPath(dbpath string) string {
+	return dbpath + ".lock"
+}
+
+// writeLockOwner records the current "PID@host" next to the db.
+func writeLockOwner(dbpath string) error {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown host"
+	}
+	owner := fmt.Sprintf("PID %d@%s", os.Getpid(), host)
+	return os.WriteFile(lockOwnerPath(dbpath), []byte(owner), 0660)
+}
+
+// lockOwner returns a description of the process holding the db lock.
+func lockOwner(dbpath string) string {
+	bs, err := os.ReadFile(lockOwnerPath(dbpath))
+	if err != nil || len(bs) == 0 {
+		return "another process"
+	}
+	return string(bs)
+}
+
 // Add updates an alias entry or adds a new alias entry. If the alias already
 // exists it is just overwritten.
 func (a *Aliases) Add(alias, mac, iface string) error {
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
 
-	// Create a buffer to store the encoded MAC, interface pair.
-	buf, err := EncodeFromMacIface(mac, iface)
+	if a.readOnly {
+		return errReadOnly
+	}
+
+	return a.db.Update(func(tx *bolt.Tx) error {
+		return putAlias(tx.Bucket([]byte(bucketName)), alias, MacIface{Mac: mac, Iface: iface}, nil)
+	})
+}
+
+// AddAll adds all of `entries` in a single transaction, so either all of them
+// are stored or none are. Existing aliases are only overwritten if `overwrite`
+// is set, otherwise an error naming them is returned. `fn`, if not nil, is
+// applied to each entry before it is written.
+func (a *Aliases) AddAll(entries map[string]MacIface, overwrite bool, fn func(*MacIface)) error {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	if a.readOnly {
+		return errReadOnly
+	}
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return a.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketName))
+		if !overwrite {
+			var existing []string
+			for _, name := range names {
+				if bucket.Get([]byte(name)) != nil {
+					existing = append(existing, name)
+				}
+			}
+			if len(existing) > 0 {
+				return &cliError{Code: codeInvalidArgument, Param: "force",
+					Message: fmt.Sprintf("aliases already exist: %s", strings.Join(existing, ", ")),
+					Hints:   []string{"pass --force to overwrite them"}}
+			}
+		}
+
+		for _, name := range names {
+			if err := putAlias(bucket, name, entries[name], fn); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// putAlias writes `entry` to `bucket`. If the alias already exists, its MAC
+// and interface are overwritten but any other fields stored with it are kept.
+// `fn`, if not nil, is applied to the entry before it is written.
+func putAlias(bucket *bolt.Bucket, alias string, entry MacIface, fn func(*MacIface)) error {
+	if value := bucket.Get([]byte(alias)); value != nil {
+		existing, err := DecodeToMacIface(bytes.NewBuffer(value))
+		if err != nil {
+			return err
+		}
+		entry.Fields = existing.Fields
+	}
+	if fn != nil {
+		fn(&entry)
+	}
+
+	// Create a buffer to store the encoded entry.
+	buf, err := EncodeMacIface(entry)
 	if err != nil {
 		return err
 	}
+	return bucket.Put([]byte(alias), buf.Bytes())
+}
+
+// Update modifies an existing alias entry in place using `fn`. The entry is
+// only written back if `fn` succeeds.
+func (a *Aliases) Update(alias string, fn func(*MacIface) error) error {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	if a.readOnly {
+		return errReadOnly
+	}
 
-	// We don't have to worry about the key existing, as we will update it
-	// provided it exists.
 	return a.db.Update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(bucketName))
+		value := bucket.Get([]byte(alias))
+		if value == nil {
+			return fmt.Errorf("alias (%s) not found in db", alias)
+		}
+
+		entry, err := DecodeToMacIface(bytes.NewBuffer(value))
+		if err != nil {
+			return err
+		}
+		if err := fn(&entry); err != nil {
+			return err
+		}
+
+		buf, err := EncodeMacIface(entry)
+		if err != nil {
+			return err
+		}
 		return bucket.Put([]byte(alias), buf.Bytes())
 	})
 }
@@ -109,6 +339,10 @@ func (a *Aliases) Del(alias string) error {
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
 
+	if a.readOnly {
+		return errReadOnly
+	}
+
 	return a.db.Update(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(bucketName))
 		return bucket.Delete([]byte(alias))
@@ -125,6 +359,9 @@ func (a *Aliases) Get(alias string) (MacIface, error) {
 		var err error
 
 		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return fmt.Errorf("alias (%s) not found in db", alias)
+		}
 		value := bucket.Get([]byte(alias))
 		if value == nil {
 			return fmt.Errorf("alias (%s) not found in db", alias)
@@ -144,6 +381,9 @@ func (a *Aliases) List() (map[string]MacIface, error) {
 	aliasMap := make(map[string]MacIface, 1)
 	err := a.db.View(func(tx *bolt.Tx) error {
 		bucket := tx.Bucket([]byte(bucketName))
+		if bucket == nil {
+			return nil
+		}
 		cursor := bucket.Cursor()
 		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
 			if entry, err := DecodeToMacIface(bytes.NewBuffer(v)); err == nil {
@@ -157,10 +397,55 @@ func (a *Aliases) List() (map[string]MacIface, error) {
 	return aliasMap, err
 }
 
+// RecordWake stores `t` as the last time a magic packet was sent to `mac`. This
+// is bookkeeping only, and is silently skipped for a read-only db.
+func (a *Aliases) RecordWake(mac string, t time.Time) error {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	if a.readOnly {
+		return nil
+	}
+
+	value, err := t.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	return a.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(wakesBucketName))
+		return bucket.Put([]byte(normalizeMAC(mac)), value)
+	})
+}
+
+// LastWake returns the last time a magic packet was sent to `mac`. The zero
+// time is returned if no wake has been recorded.
+func (a *Aliases) LastWake(mac string) (time.Time, error) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	var t time.Time
+	err := a.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(wakesBucketName))
+		if bucket == nil {
+			return nil
+		}
+		value := bucket.Get([]byte(normalizeMAC(mac)))
+		if value == nil {
+			return nil
+		}
+		return t.UnmarshalBinary(value)
+	})
+	return t, err
+}
+
 // Close closes the alias store.
 func (a *Aliases) Close() error {
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
 
+	if !a.readOnly {
+		os.Remove(lockOwnerPath(a.path))
+	}
 	return a.db.Close()
 }
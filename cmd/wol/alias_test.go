@@ -5,13 +5,18 @@ package main
 import (
 	"bytes"
 	"encoding/gob"
+	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+
+	bolt "go.etcd.io/bbolt"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -25,8 +30,8 @@ var reStripFnPreamble = regexp.MustCompile(`^.*\.(.*)$`)
 // Validate the DecodeToMacIface function.
 func TestDecodeToMacIface(t *testing.T) {
 	var TestCases = []MacIface{
-		{"00:00:00:00:00:00", ""},
-		{"00:00:00:00:00:AA", "eth1"},
+		{Mac: "00:00:00:00:00:00", Iface: ""},
+		{Mac: "00:00:00:00:00:AA", Iface: "eth1"},
 	}
 
 	for _, entry := range TestCases {
@@ -45,8 +50,8 @@ func TestDecodeToMacIface(t *testing.T) {
 // Validate the EncodeFromMacIface function.
 func TestEncodeFromMacIface(t *testing.T) {
 	var TestCases = []MacIface{
-		{"00:00:00:00:00:00", "eth0"},
-		{"00:00:00:00:00:AA", ""},
+		{Mac: "00:00:00:00:00:00", Iface: "eth0"},
+		{Mac: "00:00:00:00:00:AA", Iface: ""},
 	}
 
 	for _, entry := range TestCases {
@@ -145,6 +150,83 @@ func (suite *AliasDBTests) TestAddDuplicateAlias() {
 	assert.Equal(suite.T(), "", list["test01"].Iface)
 }
 
+// Overwriting an entry keeps the fields stored with it, even ones this binary
+// knows nothing about.
+func (suite *AliasDBTests) TestAddPreservesFields() {
+	buf, err := EncodeMacIface(MacIface{
+		Mac:    "00:11:22:33:44:55",
+		Iface:  "eth0",
+		Fields: map[string]string{"from-the-future": "keep me"},
+	})
+	assert.Nil(suite.T(), err)
+	err = suite.aliases.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(bucketName)).Put([]byte("test01"), buf.Bytes())
+	})
+	assert.Nil(suite.T(), err)
+
+	err = suite.aliases.Add("test01", "00:11:22:33:44:66", "")
+	assert.Nil(suite.T(), err)
+
+	mi, err := suite.aliases.Get("test01")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "00:11:22:33:44:66", mi.Mac)
+	assert.Equal(suite.T(), "", mi.Iface)
+	assert.Equal(suite.T(), "keep me", mi.Fields["from-the-future"])
+}
+
+// Adding many entries only overwrites existing ones when asked to, and never
+// writes some of them but not others.
+func (suite *AliasDBTests) TestAddAll() {
+	err := suite.aliases.Add("test02", "00:11:22:33:44:55", "eth0")
+	assert.Nil(suite.T(), err)
+
+	entries := map[string]MacIface{
+		"test01": {Mac: "00:11:22:33:44:01"},
+		"test02": {Mac: "00:11:22:33:44:02"},
+	}
+	tag := func(mi *MacIface) { addTags(mi, "lab") }
+	err = suite.aliases.AddAll(entries, false, tag)
+	assert.NotNil(suite.T(), err)
+	assert.Contains(suite.T(), err.Error(), "test02")
+
+	list, err := suite.aliases.List()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, len(list))
+	assert.Equal(suite.T(), "00:11:22:33:44:55", list["test02"].Mac)
+
+	err = suite.aliases.AddAll(entries, true, tag)
+	assert.Nil(suite.T(), err)
+
+	list, err = suite.aliases.List()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, len(list))
+	assert.Equal(suite.T(), "00:11:22:33:44:02", list["test02"].Mac)
+	assert.Equal(suite.T(), []string{"lab"}, list["test01"].Tags())
+}
+
+// Updating an entry modifies it in place, and tags are not duplicated.
+func (suite *AliasDBTests) TestUpdateAlias() {
+	err := suite.aliases.Add("test01", "00:11:22:33:44:55", "eth0")
+	assert.Nil(suite.T(), err)
+
+	for i := 0; i < 2; i++ {
+		err = suite.aliases.Update("test01", func(mi *MacIface) error {
+			addTags(mi, "lab", "rack1")
+			return nil
+		})
+		assert.Nil(suite.T(), err)
+	}
+
+	mi, err := suite.aliases.Get("test01")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "00:11:22:33:44:55", mi.Mac)
+	assert.Equal(suite.T(), []string{"lab", "rack1"}, mi.Tags())
+
+	// Missing aliases can not be updated.
+	err = suite.aliases.Update("foobar", func(mi *MacIface) error { return nil })
+	assert.NotNil(suite.T(), err)
+}
+
 // Adding a duplicate entry should overwrite the original one.
 func (suite *AliasDBTests) TestDeleteAlias() {
 	var err error
@@ -201,6 +283,67 @@ func (suite *AliasDBTests) TestGetAlias() {
 	assert.NotNil(suite.T(), err)
 }
 
+// Wake times are recorded per MAC, regardless of the MAC's formatting.
+func (suite *AliasDBTests) TestRecordWake() {
+	last, err := suite.aliases.LastWake("00:11:22:33:44:55")
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), last.IsZero())
+
+	now := time.Now()
+	err = suite.aliases.RecordWake("00:11:22:33:44:55", now)
+	assert.Nil(suite.T(), err)
+
+	last, err = suite.aliases.LastWake("00-11-22-33-44-55")
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), now.Equal(last))
+
+	last, err = suite.aliases.LastWake("00:11:22:33:44:66")
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), last.IsZero())
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// A read-only db can be read from, but rejects mutations.
+func TestReadOnlyAliases(t *testing.T) {
+	dbpath := filepath.Join(t.TempDir(), "bolt.db")
+
+	aliases, err := LoadAliases(dbpath)
+	assert.Nil(t, err)
+	assert.Nil(t, aliases.Add("one", "00:11:22:33:44:55", ""))
+	assert.Nil(t, aliases.Close())
+
+	aliases, err = LoadAliasesWithOptions(dbpath, AliasOptions{ReadOnly: true})
+	assert.Nil(t, err)
+	defer aliases.Close()
+
+	mi, err := aliases.Get("one")
+	assert.Nil(t, err)
+	assert.Equal(t, "00:11:22:33:44:55", mi.Mac)
+
+	assert.Equal(t, errReadOnly, aliases.Add("two", "00:11:22:33:44:66", ""))
+	assert.Equal(t, errReadOnly, aliases.Del("one"))
+	assert.Nil(t, aliases.RecordWake("00:11:22:33:44:55", time.Now()))
+}
+
+// Opening a db which is locked by someone else should time out and report who
+// holds the lock.
+func TestLockedAliases(t *testing.T) {
+	dbpath := filepath.Join(t.TempDir(), "bolt.db")
+
+	aliases, err := LoadAliases(dbpath)
+	assert.Nil(t, err)
+
+	_, err = LoadAliasesWithOptions(dbpath, AliasOptions{Timeout: 50 * time.Millisecond})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), fmt.Sprintf("PID %d@", os.Getpid()))
+	}
+
+	assert.Nil(t, aliases.Close())
+	_, err = os.Stat(lockOwnerPath(dbpath))
+	assert.True(t, os.IsNotExist(err))
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 
 // Group up all the test suites we wish to run and dispatch them here.
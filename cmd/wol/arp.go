@@ -0,0 +1,65 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+
+	"github.com/sabhiram/go-wol/wol"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// staticARPCommand returns the command which installs a permanent ARP entry
+// mapping `ip` to `mac`. This is understood by most Linux and BSD based
+// gateways (including busybox based ones). Neither understands dash separated
+// MACs, so `mac` is always written in its colon separated form.
+func staticARPCommand(ip, mac string) string {
+	return fmt.Sprintf("arp -s %s %s", ip, normalizeMAC(mac))
+}
+
+// staticNeighCommand returns the iproute2 equivalent of `staticARPCommand`.
+func staticNeighCommand(ip, mac string) string {
+	return fmt.Sprintf("ip neigh replace %s lladdr %s nud permanent dev <lan interface>", ip, normalizeMAC(mac))
+}
+
+// Run the arp command. This prints (or installs over ssh) the static ARP entry
+// a gateway needs to forward unicast magic packets to a sleeping host.
+func arpCmd(ctx context.Context, args []string, aliases *Aliases) error {
+	if len(args) < 2 {
+		return usageError("", "arp command requires a <mac address | alias> and an <ip>")
+	}
+
+	mi, err := lookupTarget(ctx, args[0], aliases)
+	if err != nil {
+		return err
+	}
+	macAddr, ip := mi.Mac, args[1]
+
+	// Validate the MAC the same way the magic packet does.
+	if _, err := wol.New(macAddr); err != nil {
+		return invalidMACError(err)
+	}
+	macAddr = normalizeMAC(macAddr)
+	if parsed := net.ParseIP(ip); parsed == nil || parsed.To4() == nil {
+		return &cliError{Code: codeInvalidArgument, Param: "ip",
+			Message: fmt.Sprintf("%s is not a valid IPv4 address", ip)}
+	}
+
+	if cliFlags.SSHTarget == "" {
+		fmt.Printf("To add a static ARP entry for %s on your gateway run one of:\n", macAddr)
+		fmt.Printf("    %s\n", staticARPCommand(ip, macAddr))
+		fmt.Printf("    %s\n", staticNeighCommand(ip, macAddr))
+		return nil
+	}
+
+	remote := staticARPCommand(ip, macAddr)
+	fmt.Printf("Running \"%s\" on %s\n", remote, cliFlags.SSHTarget)
+	cmd := exec.CommandContext(ctx, "ssh", cliFlags.SSHTarget, remote)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
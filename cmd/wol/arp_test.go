@@ -0,0 +1,24 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestStaticARPCommands(t *testing.T) {
+	assert.Equal(t, "arp -s 10.0.0.5 00:11:22:33:44:55",
+		staticARPCommand("10.0.0.5", "00:11:22:33:44:55"))
+	assert.Equal(t, "ip neigh replace 10.0.0.5 lladdr 00:11:22:33:44:55 nud permanent dev <lan interface>",
+		staticNeighCommand("10.0.0.5", "00:11:22:33:44:55"))
+
+	// Dash separated MACs are rewritten in the colon form both commands accept.
+	assert.Equal(t, "arp -s 10.0.0.5 01:23:45:ab:cd:ef",
+		staticARPCommand("10.0.0.5", "01-23-45-AB-CD-EF"))
+	assert.Equal(t, "ip neigh replace 10.0.0.5 lladdr 01:23:45:ab:cd:ef nud permanent dev <lan interface>",
+		staticNeighCommand("10.0.0.5", "01-23-45-AB-CD-EF"))
+}
@@ -0,0 +1,172 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+const (
+	// sshField is the alias metadata key holding the `user@host` to check
+	// the alias's NIC over.
+	sshField = "ssh"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// ethtoolCheckCommand returns a shell command which finds the network device
+// with `mac` and prints its ethtool settings. Reading the Wake-on setting
+// usually needs root, so sudo is tried first (without prompting).
+func ethtoolCheckCommand(mac string) string {
+	return fmt.Sprintf(`dev=$(grep -il '%s' /sys/class/net/*/address | head -n1 | cut -d/ -f5); `+
+		`[ -n "$dev" ] || { echo "no device with mac %s" >&2; exit 1; }; `+
+		`echo "Device: $dev"; sudo -n ethtool "$dev" 2>/dev/null || ethtool "$dev"`, mac, mac)
+}
+
+// ethtoolFixCommand returns the command which arms magic packet wakes on
+// `dev`. This does not survive a reboot.
+func ethtoolFixCommand(dev string) string {
+	return fmt.Sprintf("sudo -n ethtool -s %s wol g", shellQuote(dev))
+}
+
+// persistWOLCommand returns a shell script which keeps magic packet wakes
+// enabled on `dev` across reboots. NetworkManager is configured when it
+// manages the device, otherwise a systemd .link file matching `mac` is
+// installed (keeping the default interface naming). TLP, which disables
+// wake-on-lan by default on some setups, is told not to.
+func persistWOLCommand(dev, mac string) string {
+	link := shellQuote(fmt.Sprintf("/etc/systemd/network/50-wol-%s.link", dev))
+	return strings.Join([]string{
+		fmt.Sprintf(`if command -v nmcli >/dev/null && conn=$(nmcli -g GENERAL.CONNECTION device show %s 2>/dev/null) && [ -n "$conn" ]; then`, shellQuote(dev)),
+		`  sudo -n nmcli connection modify "$conn" 802-3-ethernet.wake-on-lan magic && echo "networkmanager: enabled wake-on-lan for connection $conn"`,
+		`else`,
+		fmt.Sprintf(`  printf '[Match]\nMACAddress=%s\n\n[Link]\nNamePolicy=keep kernel database onboard slot path\nMACAddressPolicy=persistent\nWakeOnLan=magic\n' | sudo -n tee %s >/dev/null && echo systemd: installed %s`, mac, link, link),
+		`fi || exit 1`,
+		`if [ -f /etc/tlp.conf ]; then`,
+		`  { sudo -n sed -i 's/^#\?WOL_DISABLE=.*/WOL_DISABLE=N/' /etc/tlp.conf && grep -q '^WOL_DISABLE=N' /etc/tlp.conf || echo WOL_DISABLE=N | sudo -n tee -a /etc/tlp.conf >/dev/null; } && echo "tlp: set WOL_DISABLE=N"`,
+		`fi`,
+	}, "\n")
+}
+
+// shellQuote quotes `s` as a single word for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// wolSettings are the Wake-on-LAN settings of a NIC, as reported by ethtool.
+// Each mode is a single letter, `g` being magic packet and `d` disabled.
+type wolSettings struct {
+	Device   string
+	Supports string
+	WakeOn   string
+}
+
+// parseEthtool extracts the Wake-on-LAN settings from the output of
+// `ethtoolCheckCommand`.
+func parseEthtool(output string) wolSettings {
+	var ws wolSettings
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Device":
+			ws.Device = value
+		case "Supports Wake-on":
+			ws.Supports = value
+		case "Wake-on":
+			ws.WakeOn = value
+		}
+	}
+	return ws
+}
+
+// sshOutput runs `remote` on `target` and returns its output. The target may
+// come from alias metadata, so it is never read as an ssh option.
+func sshOutput(ctx context.Context, target, remote string) (string, error) {
+	tracef("running %q on %s", remote, target)
+	cmd := exec.CommandContext(ctx, "ssh", "--", target, remote)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// Run the check command. This logs into the target over ssh and checks that
+// its NIC is armed for magic packets, optionally arming it with `--fix`.
+func checkCmd(ctx context.Context, args []string, aliases *Aliases) error {
+	if len(args) < 1 {
+		return usageError("", "check command requires a <mac address | alias>")
+	}
+
+	mi, err := lookupTarget(ctx, args[0], aliases)
+	if err != nil {
+		return err
+	}
+	// The MAC ends up in a shell command on the remote host, so it must
+	// really be one.
+	if _, err := net.ParseMAC(mi.Mac); err != nil {
+		return invalidMACError(err)
+	}
+	target := cliFlags.SSHTarget
+	if target == "" {
+		target = mi.Fields[sshField]
+	}
+	if target == "" {
+		return &cliError{Code: codeUsage, Param: "ssh",
+			Message: fmt.Sprintf("no ssh target to check %s on", args[0]),
+			Hints:   []string{"pass --ssh user@host, or store it with \"wol alias set <alias> ssh=user@host\""}}
+	}
+
+	out, err := sshOutput(ctx, target, ethtoolCheckCommand(normalizeMAC(mi.Mac)))
+	if err != nil {
+		return fmt.Errorf("failed to query %s: %v", target, err)
+	}
+	ws := parseEthtool(out)
+	if ws.Supports == "" || ws.WakeOn == "" {
+		return &cliError{Code: codeError, Message: fmt.Sprintf("ethtool on %s did not report Wake-on settings for %s", target, ws.Device),
+			Hints: []string{"the ssh user needs root or passwordless sudo to read Wake-on settings"}}
+	}
+
+	fmt.Printf("%s on %s: supports %q, wake-on %q\n", ws.Device, target, ws.Supports, ws.WakeOn)
+	switch {
+	case strings.Contains(ws.WakeOn, "g") && !cliFlags.Fix:
+		fmt.Printf("Magic packet wakes are enabled\n")
+		return nil
+	case !strings.Contains(ws.Supports, "g"):
+		return &cliError{Code: codeError, Message: fmt.Sprintf("%s does not support magic packet wakes", ws.Device)}
+	case !cliFlags.Fix:
+		return &cliError{Code: codeError, Message: fmt.Sprintf("magic packet wakes are disabled on %s", ws.Device),
+			Hints: []string{fmt.Sprintf("run \"wol check %s --fix\" to enable them", args[0])}}
+	}
+
+	if !strings.Contains(ws.WakeOn, "g") {
+		fmt.Printf("Running \"%s\" on %s\n", ethtoolFixCommand(ws.Device), target)
+		if _, err := sshOutput(ctx, target, ethtoolFixCommand(ws.Device)); err != nil {
+			return fmt.Errorf("failed to enable wake-on-lan on %s: %v", target, err)
+		}
+	}
+
+	// ethtool settings are lost on reboot (and some power managers turn
+	// wake-on-lan off again), so make the setting stick as well.
+	fmt.Printf("Making wake-on-lan persistent on %s\n", target)
+	out, err = sshOutput(ctx, target, persistWOLCommand(ws.Device, normalizeMAC(mi.Mac)))
+	if err != nil {
+		return fmt.Errorf("failed to make wake-on-lan persistent on %s: %v", target, err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fmt.Printf("    %s\n", line)
+	}
+	fmt.Printf("Magic packet wakes are enabled\n")
+	return nil
+}
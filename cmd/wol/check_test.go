@@ -0,0 +1,49 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestParseEthtool(t *testing.T) {
+	output := `Device: enp3s0
+Settings for enp3s0:
+	Supported ports: [ TP ]
+	Speed: 1000Mb/s
+	Supports Wake-on: pumbg
+	Wake-on: d
+	Current message level: 0x00000007 (7)
+	Link detected: yes
+`
+	assert.Equal(t, wolSettings{Device: "enp3s0", Supports: "pumbg", WakeOn: "d"}, parseEthtool(output))
+	assert.Equal(t, wolSettings{}, parseEthtool("Cannot get wake-on-lan settings"))
+}
+
+func TestEthtoolCommands(t *testing.T) {
+	assert.Contains(t, ethtoolCheckCommand("00:11:22:33:44:55"), "grep -il '00:11:22:33:44:55' /sys/class/net/*/address")
+	assert.Equal(t, "sudo -n ethtool -s 'enp3s0' wol g", ethtoolFixCommand("enp3s0"))
+
+	// Device names are parsed from the remote output, and must not be able
+	// to break out of the command.
+	assert.Equal(t, `sudo -n ethtool -s 'x'\''; reboot; '\''' wol g`, ethtoolFixCommand("x'; reboot; '"))
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, `'eth0'`, shellQuote("eth0"))
+	assert.Equal(t, `''`, shellQuote(""))
+	assert.Equal(t, `'it'\''s $(here)'`, shellQuote("it's $(here)"))
+}
+
+func TestPersistWOLCommand(t *testing.T) {
+	script := persistWOLCommand("enp3s0", "00:11:22:33:44:55")
+	assert.Contains(t, script, "nmcli -g GENERAL.CONNECTION device show 'enp3s0'")
+	assert.Contains(t, script, "802-3-ethernet.wake-on-lan magic")
+	assert.Contains(t, script, `MACAddress=00:11:22:33:44:55\n`)
+	assert.Contains(t, script, "'/etc/systemd/network/50-wol-enp3s0.link'")
+	assert.Contains(t, script, "WOL_DISABLE=N")
+}
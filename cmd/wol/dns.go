@@ -0,0 +1,80 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+const (
+	defaultDNSPort = "53"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// dnsServerAddr normalizes a user supplied DNS server into a "host:port" pair.
+// If no port was given, the default DNS port is assumed.
+func dnsServerAddr(server string) string {
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server
+	}
+	return net.JoinHostPort(server, defaultDNSPort)
+}
+
+// resolverFor returns a resolver which sends all of its queries to `server`.
+// An empty server implies the system default resolver.
+func resolverFor(server string) *net.Resolver {
+	if server == "" {
+		return net.DefaultResolver
+	}
+
+	addr := dnsServerAddr(server)
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+// resolveUDPAddr behaves like `net.ResolveUDPAddr` except that hostnames are
+// looked up using the resolver specified by the `--dns` option (if any).
+func resolveUDPAddr(ctx context.Context, hostport string) (*net.UDPAddr, error) {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return nil, err
+	}
+
+	portNum, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port (%s): %v", port, err)
+	}
+
+	// Literal IPs (including IPv6 addresses with a zone) never need a lookup.
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return &net.UDPAddr{IP: addr.AsSlice(), Port: portNum, Zone: addr.Zone()}, nil
+	}
+
+	addrs, err := resolverFor(cliFlags.DNSServer).LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prefer IPv4 addresses since that is where broadcasts live.
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			return &net.UDPAddr{IP: addr.IP, Port: portNum, Zone: addr.Zone}, nil
+		}
+	}
+	if len(addrs) > 0 {
+		return &net.UDPAddr{IP: addrs[0].IP, Port: portNum, Zone: addrs[0].Zone}, nil
+	}
+	return nil, fmt.Errorf("no addresses found for host (%s)", host)
+}
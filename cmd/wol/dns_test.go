@@ -0,0 +1,46 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestDNSServerAddr(t *testing.T) {
+	for _, tc := range []struct {
+		server, expected string
+	}{
+		{"192.168.1.1", "192.168.1.1:53"},
+		{"192.168.1.1:5353", "192.168.1.1:5353"},
+		{"::1", "[::1]:53"},
+		{"[::1]:5353", "[::1]:5353"},
+		{"dns.lab", "dns.lab:53"},
+	} {
+		assert.Equal(t, tc.expected, dnsServerAddr(tc.server))
+	}
+}
+
+func TestResolveUDPAddrLiteral(t *testing.T) {
+	addr, err := resolveUDPAddr(context.Background(), "255.255.255.255:9")
+	assert.Nil(t, err)
+	assert.True(t, addr.IP.Equal(net.IPv4bcast))
+	assert.Equal(t, 9, addr.Port)
+
+	_, err = resolveUDPAddr(context.Background(), "255.255.255.255:port")
+	assert.NotNil(t, err)
+
+	_, err = resolveUDPAddr(context.Background(), "255.255.255.255")
+	assert.NotNil(t, err)
+
+	addr, err = resolveUDPAddr(context.Background(), net.JoinHostPort("ff02::1%eth0", "9"))
+	assert.Nil(t, err)
+	assert.True(t, addr.IP.Equal(net.IPv6linklocalallnodes))
+	assert.Equal(t, "eth0", addr.Zone)
+	assert.Equal(t, "[ff02::1%eth0]:9", addr.String())
+}
@@ -0,0 +1,122 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Error codes reported by `--json`. These are part of the CLI's interface, so
+// existing codes must not be renamed.
+const (
+	codeError             = "error"
+	codeUsage             = "usage"
+	codeInvalidArgument   = "invalid_argument"
+	codeInvalidMAC        = "invalid_mac"
+	codeInterfaceNotFound = "interface_not_found"
+	codeInterfaceUnusable = "interface_unusable"
+	codeDBCorrupt         = "db_corrupt"
+	codeDBLocked          = "db_locked"
+	codeReadOnly          = "read_only"
+	codeTimeout           = "timeout"
+	codeInterrupted       = "interrupted"
+	codeUnreachable       = "unreachable"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// cliError is an error which carries enough structure to be reported to
+// wrapper scripts: a stable code, the offending parameter (if any) and hints
+// on how to fix it.
+type cliError struct {
+	Code    string   `json:"code"`
+	Message string   `json:"message"`
+	Param   string   `json:"param,omitempty"`
+	Hints   []string `json:"hints,omitempty"`
+
+	err error
+}
+
+func (e *cliError) Error() string {
+	return e.Message
+}
+
+func (e *cliError) Unwrap() error {
+	return e.err
+}
+
+// usageError returns an error for a command invoked with bad arguments.
+func usageError(param, message string) *cliError {
+	return &cliError{Code: codeUsage, Param: param, Message: message}
+}
+
+// invalidMACError wraps an error returned while parsing a MAC address.
+func invalidMACError(err error) *cliError {
+	return &cliError{
+		Code:    codeInvalidMAC,
+		Param:   "mac",
+		Message: err.Error(),
+		Hints:   []string{"MAC addresses look like 01-23-45-56-67-89 or 89:AB:CD:EF:00:12"},
+		err:     err,
+	}
+}
+
+// timeoutError reports `err` as a timeout if the command's deadline (see
+// `--timeout`) has passed. Socket and resolver timeouts do not wrap
+// context.DeadlineExceeded, so this goes by the context rather than the error.
+func timeoutError(ctx context.Context, timeout time.Duration, err error) error {
+	if err == nil || ctx.Err() != context.DeadlineExceeded {
+		return err
+	}
+	return &cliError{Code: codeTimeout, Param: "timeout", err: errors.Join(err, ctx.Err()),
+		Message: fmt.Sprintf("timed out after %s: %v", timeout, err)}
+}
+
+// toCLIError classifies any error into a cliError.
+func toCLIError(err error) *cliError {
+	var ce *cliError
+	var corrupt *corruptDBError
+	var locked *lockedDBError
+	var signaled *signalError
+	switch {
+	case errors.As(err, &ce):
+		return ce
+	case errors.As(err, &corrupt):
+		return &cliError{Code: codeDBCorrupt, Message: err.Error(), err: err,
+			Hints: []string{"run again with --recover to salvage it"}}
+	case errors.As(err, &locked):
+		return &cliError{Code: codeDBLocked, Param: "lock-timeout", Message: err.Error(), err: err,
+			Hints: []string{"wait for the other process to finish, or raise --lock-timeout"}}
+	case errors.Is(err, errReadOnly):
+		return &cliError{Code: codeReadOnly, Param: "read-only", Message: err.Error(), err: err}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &cliError{Code: codeTimeout, Param: "timeout", Message: err.Error(), err: err}
+	case errors.As(err, &signaled):
+		return &cliError{Code: codeInterrupted, Message: signaled.Error(), err: err}
+	case errors.Is(err, context.Canceled):
+		return &cliError{Code: codeInterrupted, Message: "interrupted", err: err}
+	}
+	return &cliError{Code: codeError, Message: err.Error(), err: err}
+}
+
+// printError reports an error to stderr, as a JSON object when `--json` is
+// specified and as text with hints otherwise.
+func printError(err error) {
+	ce := toCLIError(err)
+	if cliFlags.JSON {
+		json.NewEncoder(os.Stderr).Encode(ce)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Fatal error: %s\n", ce.Message)
+	for _, hint := range ce.Hints {
+		fmt.Fprintf(os.Stderr, "    hint: %s\n", hint)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestToCLIError(t *testing.T) {
+	for _, tc := range []struct {
+		err   error
+		code  string
+		param string
+	}{
+		{errors.New("boom"), codeError, ""},
+		{usageError("url", "missing url"), codeUsage, "url"},
+		{invalidMACError(errors.New("bad mac")), codeInvalidMAC, "mac"},
+		{&corruptDBError{"bolt.db", errors.New("invalid database")}, codeDBCorrupt, ""},
+		{&lockedDBError{"bolt.db", "PID 1@host"}, codeDBLocked, "lock-timeout"},
+		{errReadOnly, codeReadOnly, "read-only"},
+		{fmt.Errorf("timed out: %w", context.DeadlineExceeded), codeTimeout, "timeout"},
+		{context.Canceled, codeInterrupted, ""},
+	} {
+		ce := toCLIError(tc.err)
+		assert.Equal(t, tc.code, ce.Code, tc.err.Error())
+		assert.Equal(t, tc.param, ce.Param, tc.err.Error())
+	}
+}
+
+func TestTimeoutError(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	<-ctx.Done()
+
+	// A socket deadline does not wrap context.DeadlineExceeded, but is still
+	// a timeout once the command's deadline has passed.
+	err := timeoutError(ctx, time.Second, fmt.Errorf("read udp: %w", os.ErrDeadlineExceeded))
+	ce := toCLIError(err)
+	assert.Equal(t, codeTimeout, ce.Code)
+	assert.Equal(t, "timed out after 1s: read udp: i/o timeout", ce.Message)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	// A command which succeeded (or failed before the deadline) is left alone.
+	assert.Nil(t, timeoutError(ctx, time.Second, nil))
+	boom := errors.New("boom")
+	assert.Equal(t, boom, timeoutError(context.Background(), time.Second, boom))
+}
+
+func TestCLIErrorJSON(t *testing.T) {
+	bs, err := json.Marshal(toCLIError(&corruptDBError{"bolt.db", errors.New("invalid database")}))
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{
+		"code": "db_corrupt",
+		"message": "alias db bolt.db is corrupt: invalid database",
+		"hints": ["run again with --recover to salvage it"]
+	}`, string(bs))
+}
@@ -0,0 +1,344 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// filterRecord is what a `--where` expression is evaluated against.
+type filterRecord struct {
+	Alias    string
+	Entry    MacIface
+	LastWake time.Time
+	Now      time.Time
+}
+
+// filterExpr is a parsed `--where` expression.
+type filterExpr interface {
+	match(r filterRecord) bool
+}
+
+type andExpr struct{ left, right filterExpr }
+type orExpr struct{ left, right filterExpr }
+type notExpr struct{ expr filterExpr }
+type cmpExpr struct{ field, op, value string }
+
+func (e andExpr) match(r filterRecord) bool { return e.left.match(r) && e.right.match(r) }
+func (e orExpr) match(r filterRecord) bool  { return e.left.match(r) || e.right.match(r) }
+func (e notExpr) match(r filterRecord) bool { return !e.expr.match(r) }
+
+// match compares a single field of the record. `tag` matches if any of the
+// alias's tags compare true (so `tag!=x` means "has no tag x"). `last_wake`
+// compares the time since the last wake against a duration like `30d`. All
+// other fields are compared numerically when both sides are numbers, and as
+// strings otherwise. A field the alias does not have is treated as empty.
+func (e cmpExpr) match(r filterRecord) bool {
+	switch e.field {
+	case "tag":
+		if e.op == "!=" {
+			return !(cmpExpr{e.field, "=", e.value}).match(r)
+		}
+		for _, tag := range r.Entry.Tags() {
+			if compareValues(tag, e.op, e.value) {
+				return true
+			}
+		}
+		return false
+
+	case "last_wake":
+		// A host which was never woken is infinitely long ago.
+		limit, _ := parseAge(e.value)
+		if r.LastWake.IsZero() {
+			return e.op == ">" || e.op == ">=" || e.op == "!="
+		}
+		age := r.Now.Sub(r.LastWake)
+		return compareValues(strconv.FormatInt(int64(age), 10), e.op, strconv.FormatInt(int64(limit), 10))
+	}
+
+	var actual string
+	switch e.field {
+	case "alias", "name":
+		actual = r.Alias
+	case "mac":
+		actual, e.value = normalizeMAC(r.Entry.Mac), normalizeMAC(e.value)
+	case "iface":
+		actual = r.Entry.Iface
+	default:
+		actual = r.Entry.Fields[e.field]
+	}
+	return compareValues(actual, e.op, e.value)
+}
+
+// builtinFields are the fields every alias has.
+var builtinFields = map[string]bool{
+	"alias": true, "name": true, "mac": true, "iface": true, "tag": true, "last_wake": true,
+}
+
+// filterFields returns the fields compared by the expression.
+func filterFields(expr filterExpr) []string {
+	switch e := expr.(type) {
+	case andExpr:
+		return append(filterFields(e.left), filterFields(e.right)...)
+	case orExpr:
+		return append(filterFields(e.left), filterFields(e.right)...)
+	case notExpr:
+		return filterFields(e.expr)
+	case cmpExpr:
+		return []string{e.field}
+	}
+	return nil
+}
+
+// compareValues applies `op` to two values.
+func compareValues(actual, op, value string) bool {
+	cmp := strings.Compare(actual, value)
+	a, aerr := strconv.ParseFloat(actual, 64)
+	v, verr := strconv.ParseFloat(value, 64)
+	if aerr == nil && verr == nil {
+		switch {
+		case a < v:
+			cmp = -1
+		case a > v:
+			cmp = 1
+		default:
+			cmp = 0
+		}
+	}
+
+	switch op {
+	case "=", "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// parseAge parses a duration which, in addition to what `time.ParseDuration`
+// accepts, may use `d` (days) and `w` (weeks) as its unit.
+func parseAge(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, err := strconv.ParseFloat(strings.TrimSuffix(s, suffix), 64); strings.HasSuffix(s, suffix) && err == nil {
+			return time.Duration(n * float64(unit)), nil
+		}
+	}
+	return time.ParseDuration(s)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// tokenizeFilter splits an expression into identifiers / values, operators
+// and parentheses. Values may be quoted with single or double quotes.
+func tokenizeFilter(s string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, string(c))
+			i++
+		case strings.HasPrefix(s[i:], "&&"), strings.HasPrefix(s[i:], "||"),
+			strings.HasPrefix(s[i:], "=="), strings.HasPrefix(s[i:], "!="),
+			strings.HasPrefix(s[i:], "<="), strings.HasPrefix(s[i:], ">="):
+			tokens = append(tokens, s[i:i+2])
+			i += 2
+		case c == '=' || c == '<' || c == '>' || c == '!':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(s[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quote in filter")
+			}
+			// Quoted values keep their quote so they are never operators.
+			tokens = append(tokens, s[i:i+end+2])
+			i += end + 2
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t()=<>!&|'\"", rune(s[j])) {
+				j++
+			}
+			if j == i {
+				return nil, fmt.Errorf("unexpected %q in filter", s[i:])
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *filterParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *filterParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// or := and ( "||" and )*
+func (p *filterParser) parseOr() (filterExpr, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.peek() == "||" {
+		p.next()
+		var right filterExpr
+		right, err = p.parseAnd()
+		left = orExpr{left, right}
+	}
+	return left, err
+}
+
+// and := unary ( "&&" unary )*
+func (p *filterParser) parseAnd() (filterExpr, error) {
+	left, err := p.parseUnary()
+	for err == nil && p.peek() == "&&" {
+		p.next()
+		var right filterExpr
+		right, err = p.parseUnary()
+		left = andExpr{left, right}
+	}
+	return left, err
+}
+
+// unary := "!" unary | "(" or ")" | field op value
+func (p *filterParser) parseUnary() (filterExpr, error) {
+	switch p.peek() {
+	case "!":
+		p.next()
+		expr, err := p.parseUnary()
+		return notExpr{expr}, err
+	case "(":
+		p.next()
+		expr, err := p.parseOr()
+		if err == nil && p.next() != ")" {
+			err = fmt.Errorf("missing ) in filter")
+		}
+		return expr, err
+	}
+
+	field, op, value := p.next(), p.next(), p.next()
+	if !isFilterWord(field) || !isFilterWord(value) {
+		return nil, fmt.Errorf("expected <field> <op> <value> in filter")
+	}
+	switch op {
+	case "=", "==", "!=", "<", "<=", ">", ">=":
+	default:
+		return nil, fmt.Errorf("unknown operator %q in filter", op)
+	}
+
+	if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') {
+		value = value[1 : len(value)-1]
+	}
+	field = strings.ToLower(field)
+	if field == "last_wake" {
+		if _, err := parseAge(value); err != nil {
+			return nil, fmt.Errorf("last_wake must be compared to a duration (eg. 30d): %v", err)
+		}
+	}
+	return cmpExpr{field, op, value}, nil
+}
+
+// isFilterWord returns true if the token is an identifier or value (rather
+// than an operator or parenthesis).
+func isFilterWord(t string) bool {
+	if t == "" {
+		return false
+	}
+	return unicode.IsLetter(rune(t[0])) || unicode.IsDigit(rune(t[0])) ||
+		!strings.ContainsRune("()=<>!&|", rune(t[0]))
+}
+
+// parseFilter parses a `--where` expression such as
+// `tag=storage && (iface=eth1 || location='rack 2')`.
+func parseFilter(s string) (filterExpr, error) {
+	tokens, err := tokenizeFilter(s)
+	if err == nil && len(tokens) == 0 {
+		err = fmt.Errorf("empty filter")
+	}
+
+	var expr filterExpr
+	if err == nil {
+		p := &filterParser{tokens: tokens}
+		expr, err = p.parseOr()
+		if err == nil && p.pos < len(tokens) {
+			err = fmt.Errorf("unexpected %q in filter", p.peek())
+		}
+	}
+	if err != nil {
+		return nil, usageError("where", err.Error())
+	}
+	return expr, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// selectAliases returns the (sorted) names of the aliases which match the
+// `--where` expression. All aliases match when no expression is given. Fields
+// which no alias has are an error, so that a typo (`tga!=x`) does not match
+// every alias.
+func selectAliases(where string, aliases *Aliases, all map[string]MacIface) ([]string, error) {
+	var expr filterExpr
+	if where != "" {
+		var err error
+		if expr, err = parseFilter(where); err != nil {
+			return nil, err
+		}
+
+		known := map[string]bool{}
+		for _, mi := range all {
+			for k := range mi.Fields {
+				known[k] = true
+			}
+		}
+		for _, field := range filterFields(expr) {
+			if !builtinFields[field] && !known[field] {
+				return nil, usageError("where", fmt.Sprintf("no alias has the field %q", field))
+			}
+		}
+	}
+
+	now := time.Now()
+	var names []string
+	for alias, mi := range all {
+		if expr != nil {
+			last, err := aliases.LastWake(mi.Mac)
+			if err != nil {
+				return nil, err
+			}
+			if !expr.match(filterRecord{alias, mi, last, now}) {
+				continue
+			}
+		}
+		names = append(names, alias)
+	}
+	sort.Strings(names)
+	return names, nil
+}
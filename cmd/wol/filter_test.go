@@ -0,0 +1,106 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestFilter(t *testing.T) {
+	now := time.Now()
+	nas := filterRecord{
+		Alias: "nas",
+		Entry: MacIface{Mac: "00:11:22:33:44:55", Iface: "eth1", Fields: map[string]string{
+			"tags":     "storage,lab",
+			"location": "rack 2",
+			"watts":    "35",
+		}},
+		LastWake: now.Add(-48 * time.Hour),
+		Now:      now,
+	}
+	htpc := filterRecord{
+		Alias: "htpc",
+		Entry: MacIface{Mac: "00:11:22:33:44:66"},
+		Now:   now,
+	}
+
+	for _, tc := range []struct {
+		where     string
+		nas, htpc bool
+	}{
+		{"tag=storage", true, false},
+		{"tag!=storage", false, true},
+		{"tag=storage && iface=eth1", true, false},
+		{"tag=storage && iface=eth0", false, false},
+		{"alias=htpc || iface=eth1", true, true},
+		{"!(alias=htpc)", true, false},
+		{"location='rack 2'", true, false},
+		{`location == "rack 2"`, true, false},
+		{"watts > 9", true, false},
+		{"watts < 100 && watts >= 35", true, false},
+		{"mac=00-11-22-33-44-66", false, true},
+		{"last_wake < 30d", true, false},
+		{"last_wake < 1d", false, false},
+		{"last_wake > 1w", false, true},
+		{"missing=''", true, true},
+	} {
+		expr, err := parseFilter(tc.where)
+		if assert.Nil(t, err, tc.where) {
+			assert.Equal(t, tc.nas, expr.match(nas), tc.where)
+			assert.Equal(t, tc.htpc, expr.match(htpc), tc.where)
+		}
+	}
+}
+
+func TestFilterNegative(t *testing.T) {
+	for _, where := range []string{
+		"",
+		"tag",
+		"tag=",
+		"tag ~ storage",
+		"(tag=storage",
+		"tag=storage &&",
+		"tag=storage tag=lab",
+		"location='rack 2",
+		"last_wake < soon",
+	} {
+		_, err := parseFilter(where)
+		assert.NotNil(t, err, where)
+	}
+}
+
+func TestSelectAliases(t *testing.T) {
+	aliases, err := LoadAliases(filepath.Join(t.TempDir(), "bolt.db"))
+	assert.Nil(t, err)
+	defer aliases.Close()
+	assert.Nil(t, aliases.Add("nas", "00:11:22:33:44:55", "eth1"))
+	assert.Nil(t, aliases.Add("htpc", "00:11:22:33:44:66", ""))
+	assert.Nil(t, aliases.Update("nas", func(mi *MacIface) error {
+		mi.Fields = map[string]string{"tags": "storage", "owner": "sam"}
+		return nil
+	}))
+	all, err := aliases.List()
+	assert.Nil(t, err)
+
+	names, err := selectAliases("tag!=storage", aliases, all)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"htpc"}, names)
+
+	// A field only some aliases have is empty for the others.
+	names, err = selectAliases("owner!=sam", aliases, all)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"htpc"}, names)
+
+	// A field no alias has is most likely a typo, which must not select
+	// every alias.
+	_, err = selectAliases("tga!=storage", aliases, all)
+	assert.NotNil(t, err)
+	_, err = selectAliases("alias=nas || (owner=sam && !(onwer=sam))", aliases, all)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,123 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/sabhiram/go-wol/wol"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// generatedAlias is a single alias minted by `alias generate`.
+type generatedAlias struct {
+	Name string
+	MacIface
+}
+
+// readMACList reads one `mac [iface]` pair per line. Blank lines and lines
+// starting with `#` are skipped. Every MAC is validated.
+func readMACList(r io.Reader) ([]MacIface, error) {
+	var entries []MacIface
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if _, err := wol.New(fields[0]); err != nil {
+			return nil, invalidMACError(fmt.Errorf("mac list line %d: %v", line, err))
+		}
+
+		entry := MacIface{Mac: fields[0]}
+		if len(fields) > 1 {
+			entry.Iface = fields[1]
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// generateAliases names each of the entries using the `text/template` in
+// `tmpl`. The template is executed with `.N` (the entry's number, counting
+// from `start`), `.Mac` and `.Iface`. Generated names must be unique.
+func generateAliases(tmpl string, entries []MacIface, start int) ([]generatedAlias, error) {
+	t, err := template.New("alias").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return nil, usageError("template", fmt.Sprintf("invalid alias template: %v", err))
+	}
+
+	seen := map[string]bool{}
+	var generated []generatedAlias
+	for idx, entry := range entries {
+		var buf bytes.Buffer
+		data := struct {
+			N          int
+			Mac, Iface string
+		}{start + idx, entry.Mac, entry.Iface}
+		if err := t.Execute(&buf, data); err != nil {
+			return nil, usageError("template", fmt.Sprintf("invalid alias template: %v", err))
+		}
+
+		name := strings.TrimSpace(buf.String())
+		switch {
+		case name == "":
+			return nil, usageError("template", fmt.Sprintf("alias template produced an empty name for %s", entry.Mac))
+		case seen[name]:
+			return nil, usageError("template", fmt.Sprintf("alias template produced %s more than once", name))
+		}
+		seen[name] = true
+		generated = append(generated, generatedAlias{name, entry})
+	}
+	return generated, nil
+}
+
+// Run the alias generate command.
+func aliasGenerateCmd(aliases *Aliases) error {
+	if cliFlags.Template == "" || cliFlags.MACList == "" {
+		return usageError("", "alias generate requires a --template and a --mac-list")
+	}
+
+	f, err := os.Open(cliFlags.MACList)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	entries, err := readMACList(f)
+	if err != nil {
+		return err
+	}
+
+	// Every name is generated (and checked) before anything is written.
+	generated, err := generateAliases(cliFlags.Template, entries, cliFlags.Start)
+	if err != nil {
+		return err
+	}
+
+	// All aliases are written at once, and existing ones are only replaced
+	// with `--force`.
+	named := make(map[string]MacIface, len(generated))
+	for _, g := range generated {
+		named[g.Name] = g.MacIface
+	}
+	err = aliases.AddAll(named, cliFlags.Force, func(mi *MacIface) {
+		addTags(mi, cliFlags.Tags...)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, g := range generated {
+		fmt.Printf("    %s - %s %s\n", g.Name, g.Mac, g.Iface)
+	}
+	fmt.Printf("Generated %d aliases\n", len(generated))
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestReadMACList(t *testing.T) {
+	entries, err := readMACList(strings.NewReader(`
+# lab rack 1
+00:11:22:33:44:01
+00:11:22:33:44:02 eth1
+`))
+	assert.Nil(t, err)
+	assert.Equal(t, []MacIface{
+		{Mac: "00:11:22:33:44:01"},
+		{Mac: "00:11:22:33:44:02", Iface: "eth1"},
+	}, entries)
+
+	_, err = readMACList(strings.NewReader("not-a-mac\n"))
+	assert.NotNil(t, err)
+}
+
+func TestGenerateAliases(t *testing.T) {
+	entries := []MacIface{{Mac: "00:11:22:33:44:01"}, {Mac: "00:11:22:33:44:02"}}
+
+	generated, err := generateAliases(`lab-{{printf "%02d" .N}}`, entries, 9)
+	assert.Nil(t, err)
+	assert.Equal(t, "lab-09", generated[0].Name)
+	assert.Equal(t, "lab-10", generated[1].Name)
+	assert.Equal(t, "00:11:22:33:44:02", generated[1].Mac)
+
+	// Names must be unique and non-empty, and the template must be valid.
+	_, err = generateAliases("lab", entries, 1)
+	assert.NotNil(t, err)
+	_, err = generateAliases("", entries, 1)
+	assert.NotNil(t, err)
+	_, err = generateAliases("{{.Missing}}", entries, 1)
+	assert.NotNil(t, err)
+}
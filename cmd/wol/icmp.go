@@ -0,0 +1,57 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// icmpFeedback waits up to `wait` for an ICMP error caused by the datagram just
+// written to `conn`. The kernel reports these on the next read from a
+// connected UDP socket, so no raw socket (or privileges) are needed. Hearing
+// nothing back is not proof of delivery, only that nothing refused it. A port
+// unreachable error means the packet did arrive, so it is only noted.
+func icmpFeedback(conn net.Conn, wait time.Duration) error {
+	deadline := time.Now().Add(wait)
+	conn.SetReadDeadline(deadline)
+	defer conn.SetReadDeadline(time.Time{})
+
+	buf := make([]byte, 1)
+	_, err := conn.Read(buf)
+	var netErr net.Error
+	if err == nil || (errors.As(err, &netErr) && netErr.Timeout()) {
+		tracef("no icmp error received within %s", wait)
+		return nil
+	}
+	tracef("icmp feedback: %v", err)
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		// A port unreachable message comes from the destination itself, so
+		// the packet was delivered.
+		fmt.Printf("Note: %s reported the port as unreachable (the packet arrived, but the host may already be awake)\n", conn.RemoteAddr())
+		return nil
+	}
+	return icmpError(conn.RemoteAddr(), err)
+}
+
+// icmpError turns the error reported for an ICMP message into a cliError which
+// explains what it most likely means for a wake.
+func icmpError(dst net.Addr, err error) error {
+	var message string
+	switch {
+	case errors.Is(err, syscall.EHOSTUNREACH):
+		message = fmt.Sprintf("%s is unreachable (a router refused the packet, directed broadcasts may be prohibited)", dst)
+	case errors.Is(err, syscall.ENETUNREACH):
+		message = fmt.Sprintf("the network of %s is unreachable", dst)
+	case errors.Is(err, syscall.EACCES), errors.Is(err, syscall.EPERM):
+		message = fmt.Sprintf("sending to %s was administratively prohibited", dst)
+	default:
+		return err
+	}
+	return &cliError{Code: codeUnreachable, Param: "bcast", Message: message, err: err}
+}
@@ -0,0 +1,64 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestICMPFeedback(t *testing.T) {
+	// Find a local port nobody is listening on.
+	l, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("no loopback:", err)
+	}
+	closed := l.LocalAddr().String()
+	l.Close()
+
+	conn, err := net.Dial("udp4", closed)
+	assert.Nil(t, err)
+	defer conn.Close()
+	_, err = conn.Write(make([]byte, 102))
+	assert.Nil(t, err)
+
+	// A closed port means the packet arrived, which is not an error.
+	assert.Nil(t, icmpFeedback(conn, time.Second))
+
+	// Nothing is reported by a listening port.
+	l, err = net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer l.Close()
+	open, err := net.Dial("udp4", l.LocalAddr().String())
+	assert.Nil(t, err)
+	defer open.Close()
+	_, err = open.Write(make([]byte, 102))
+	assert.Nil(t, err)
+	assert.Nil(t, icmpFeedback(open, 50*time.Millisecond))
+}
+
+func TestICMPError(t *testing.T) {
+	dst := &net.UDPAddr{IP: net.IPv4(10, 1, 0, 255), Port: 9}
+	for _, errno := range []syscall.Errno{syscall.EHOSTUNREACH, syscall.ENETUNREACH, syscall.EACCES} {
+		err := icmpError(dst, errno)
+		var cerr *cliError
+		if assert.True(t, errors.As(err, &cerr), errno.Error()) {
+			assert.Equal(t, codeUnreachable, cerr.Code)
+			assert.Contains(t, cerr.Message, "10.1.0.255:9")
+		}
+	}
+
+	refused := fmt.Errorf("read: %w", syscall.ECONNREFUSED)
+	assert.Equal(t, refused, icmpError(dst, refused))
+
+	other := errors.New("something else")
+	assert.Equal(t, other, icmpError(dst, other))
+}
@@ -0,0 +1,237 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sabhiram/go-wol/wol"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// lanInterface is an interface which magic packets can be broadcast from.
+type lanInterface struct {
+	Name string
+	Net  *net.IPNet
+}
+
+// lanInterfaces returns the interfaces which are up and have an IPv4 network.
+func lanInterfaces() []lanInterface {
+	var lans []lanInterface
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+				lans = append(lans, lanInterface{iface.Name, ipNet})
+				break
+			}
+		}
+	}
+	return lans
+}
+
+// broadcastAddr returns the directed broadcast address of an IPv4 network.
+func broadcastAddr(n *net.IPNet) net.IP {
+	ip := n.IP.To4()
+	mask := n.Mask
+	if len(mask) == net.IPv6len {
+		mask = mask[12:]
+	}
+	bcast := make(net.IP, net.IPv4len)
+	for i := range bcast {
+		bcast[i] = ip[i] | ^mask[i]
+	}
+	return bcast
+}
+
+// prompter asks questions on `out` and reads the answers from `lines`. Waiting
+// for an answer gives up once `ctx` is done, leaving its error in `err`.
+type prompter struct {
+	ctx   context.Context
+	lines <-chan string
+	out   io.Writer
+	err   error
+}
+
+// newPrompter returns a prompter which reads the answers from `in`. Lines are
+// read in the background, since a read from a terminal can not be cancelled.
+func newPrompter(ctx context.Context, in io.Reader, out io.Writer) *prompter {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return &prompter{ctx: ctx, lines: lines, out: out}
+}
+
+// ask prints `question` and returns the answer, or `def` for an empty answer.
+// The second return value is false once the input is exhausted (or `ctx` is
+// done).
+func (p *prompter) ask(question, def string) (string, bool) {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+
+	var line string
+	ok := false
+	select {
+	case line, ok = <-p.lines:
+	case <-p.ctx.Done():
+		p.err = p.ctx.Err()
+	}
+	if !ok {
+		fmt.Fprintln(p.out)
+		return def, false
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, true
+	}
+	return def, true
+}
+
+// confirm asks a yes/no `question`, which defaults to no.
+func (p *prompter) confirm(question string) (bool, bool) {
+	answer, ok := p.ask(question+" (y/N)", "")
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes", ok
+}
+
+// runInit walks through picking the interface to wake from (saved as a site
+// profile) and adding aliases for the machines to wake. Existing sites and
+// aliases are only replaced once confirmed.
+func runInit(ctx context.Context, in io.Reader, out io.Writer, lans []lanInterface, aliases *Aliases) error {
+	p := newPrompter(ctx, in, out)
+
+	if len(lans) == 0 {
+		fmt.Fprintf(out, "No interfaces with an IPv4 network found, the OS will pick one when waking.\n")
+	} else {
+		fmt.Fprintf(out, "Interfaces which can send magic packets:\n")
+		for idx, lan := range lans {
+			fmt.Fprintf(out, "    %d) %s: %s (broadcast %s)\n", idx+1, lan.Name, lan.Net, broadcastAddr(lan.Net))
+		}
+
+		var lan lanInterface
+		for {
+			answer, ok := p.ask("Interface to use", "1")
+			if !ok {
+				return p.err
+			}
+			choice, err := strconv.Atoi(answer)
+			if err == nil && choice >= 1 && choice <= len(lans) {
+				lan = lans[choice-1]
+				break
+			}
+			fmt.Fprintf(out, "Please pick a number between 1 and %d\n", len(lans))
+		}
+
+		sites, err := aliases.Sites()
+		if err != nil {
+			return err
+		}
+		var name string
+		for {
+			var ok bool
+			if name, ok = p.ask("Site name for this network", "home"); !ok {
+				return p.err
+			}
+			if _, exists := sites[name]; !exists {
+				break
+			}
+			replace, ok := p.confirm(fmt.Sprintf("Site %s already exists (%s), replace it?", name, formatSite(sites[name])))
+			if !ok {
+				return p.err
+			}
+			if replace {
+				break
+			}
+		}
+
+		_, subnet, _ := net.ParseCIDR(lan.Net.String())
+		site := map[string]string{
+			"iface":  lan.Name,
+			"bcast":  broadcastAddr(lan.Net).String(),
+			"subnet": subnet.String(),
+		}
+		// Settings of a replaced site which are not set here are removed.
+		settings := map[string]string{}
+		for k := range siteKeys {
+			settings[k] = site[k]
+		}
+		if err := aliases.SetSite(name, settings); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Saved site %s (%s)\n", name, formatSite(site))
+	}
+
+	fmt.Fprintf(out, "Add the machines to wake (leave the alias empty to finish).\n")
+	for {
+		alias, ok := p.ask("Alias", "")
+		if !ok || alias == "" {
+			break
+		}
+		if existing, err := aliases.Get(alias); err == nil {
+			replace, ok := p.confirm(fmt.Sprintf("Alias %s already exists (%s), replace it?", alias, existing.Mac))
+			if !ok {
+				break
+			}
+			if !replace {
+				continue
+			}
+		}
+		for {
+			mac, ok := p.ask("MAC address of "+alias, "")
+			if !ok {
+				return p.err
+			}
+			if _, err := wol.New(mac); err != nil {
+				fmt.Fprintf(out, "%v\n", err)
+				continue
+			}
+			// The alias is left without an interface, so that it is woken
+			// with the interface of whichever site the host is at.
+			if err := aliases.Add(alias, mac, ""); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Saved %s\n", alias)
+			break
+		}
+	}
+	if p.err != nil {
+		return p.err
+	}
+
+	fmt.Fprintf(out, "Done. Wake a machine with \"wol <alias>\".\n")
+	return nil
+}
+
+// Run the init command.
+func initCmd(ctx context.Context, args []string, aliases *Aliases) error {
+	return runInit(ctx, os.Stdin, os.Stdout, lanInterfaces(), aliases)
+}
@@ -0,0 +1,120 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestBroadcastAddr(t *testing.T) {
+	_, n, _ := net.ParseCIDR("192.168.1.5/24")
+	assert.Equal(t, "192.168.1.255", broadcastAddr(n).String())
+	_, n, _ = net.ParseCIDR("10.1.0.0/16")
+	assert.Equal(t, "10.1.255.255", broadcastAddr(n).String())
+	n = &net.IPNet{IP: net.ParseIP("172.16.3.4"), Mask: net.CIDRMask(20, 32)}
+	assert.Equal(t, "172.16.15.255", broadcastAddr(n).String())
+}
+
+func TestRunInit(t *testing.T) {
+	aliases, err := LoadAliases(filepath.Join(t.TempDir(), "bolt.db"))
+	assert.Nil(t, err)
+	defer aliases.Close()
+
+	lans := []lanInterface{
+		{"eth0", &net.IPNet{IP: net.ParseIP("10.1.0.5"), Mask: net.CIDRMask(24, 32)}},
+		{"wlan0", &net.IPNet{IP: net.ParseIP("192.168.0.12"), Mask: net.CIDRMask(24, 32)}},
+	}
+	input := strings.Join([]string{
+		"3", // out of range
+		"2", // wlan0
+		"",  // default site name
+		"nas",
+		"not a mac",
+		"00:11:22:33:44:55",
+		"",
+	}, "\n")
+
+	var out bytes.Buffer
+	assert.Nil(t, runInit(context.Background(), strings.NewReader(input), &out, lans, aliases))
+	assert.Contains(t, out.String(), "Please pick a number between 1 and 2")
+	assert.Contains(t, out.String(), "Saved nas")
+
+	site, err := aliases.GetSite("home")
+	assert.Nil(t, err)
+	assert.Equal(t, Site{"iface": "wlan0", "bcast": "192.168.0.255", "subnet": "192.168.0.0/24"}, site)
+
+	mi, err := aliases.Get("nas")
+	assert.Nil(t, err)
+	assert.Equal(t, MacIface{Mac: "00:11:22:33:44:55"}, mi)
+}
+
+// Existing sites and aliases are only replaced once confirmed.
+func TestRunInitExisting(t *testing.T) {
+	aliases, err := LoadAliases(filepath.Join(t.TempDir(), "bolt.db"))
+	assert.Nil(t, err)
+	defer aliases.Close()
+	assert.Nil(t, aliases.SetSite("home", map[string]string{"iface": "eth9", "port": "7"}))
+	assert.Nil(t, aliases.SetSite("office", map[string]string{"iface": "eth8"}))
+	assert.Nil(t, aliases.Add("nas", "00:11:22:33:44:55", "eth9"))
+	assert.Nil(t, aliases.Add("htpc", "00:11:22:33:44:66", "eth9"))
+
+	lans := []lanInterface{
+		{"eth0", &net.IPNet{IP: net.ParseIP("10.1.0.5"), Mask: net.CIDRMask(24, 32)}},
+	}
+	input := strings.Join([]string{
+		"1",
+		"office", // exists
+		"n",      // keep it, and pick another name
+		"home",   // exists
+		"y",      // replace it
+		"nas",    // exists
+		"",       // keep it (no is the default)
+		"htpc",   // exists
+		"yes",    // replace it
+		"00:11:22:33:44:77",
+		"",
+	}, "\n")
+
+	var out bytes.Buffer
+	assert.Nil(t, runInit(context.Background(), strings.NewReader(input), &out, lans, aliases))
+
+	site, err := aliases.GetSite("office")
+	assert.Nil(t, err)
+	assert.Equal(t, Site{"iface": "eth8"}, site)
+	site, err = aliases.GetSite("home")
+	assert.Nil(t, err)
+	assert.Equal(t, Site{"iface": "eth0", "bcast": "10.1.0.255", "subnet": "10.1.0.0/24"}, site)
+
+	mi, err := aliases.Get("nas")
+	assert.Nil(t, err)
+	assert.Equal(t, "00:11:22:33:44:55", mi.Mac)
+	mi, err = aliases.Get("htpc")
+	assert.Nil(t, err)
+	assert.Equal(t, "00:11:22:33:44:77", mi.Mac)
+}
+
+// Waiting for an answer stops when the context is cancelled.
+func TestRunInitCancelled(t *testing.T) {
+	aliases, err := LoadAliases(filepath.Join(t.TempDir(), "bolt.db"))
+	assert.Nil(t, err)
+	defer aliases.Close()
+
+	// The pipe is never written to, like a terminal nobody types into.
+	r, w := io.Pipe()
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = runInit(ctx, r, io.Discard, nil, aliases)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,76 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"regexp"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+var (
+	// reAnsibleVar matches the names Ansible accepts for variables. Python
+	// keywords are not allowed either (see ansibleKeywords).
+	reAnsibleVar = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	ansibleKeywords = map[string]bool{
+		"False": true, "None": true, "True": true, "and": true, "as": true,
+		"assert": true, "async": true, "await": true, "break": true,
+		"class": true, "continue": true, "def": true, "del": true,
+		"elif": true, "else": true, "except": true, "finally": true,
+		"for": true, "from": true, "global": true, "if": true,
+		"import": true, "in": true, "is": true, "lambda": true,
+		"nonlocal": true, "not": true, "or": true, "pass": true,
+		"raise": true, "return": true, "try": true, "while": true,
+		"with": true, "yield": true,
+	}
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+type ansibleGroup struct {
+	Hosts    map[string]map[string]string `json:"hosts,omitempty"`
+	Children map[string]ansibleGroup      `json:"children,omitempty"`
+}
+
+// ansibleInventory renders the aliases as an Ansible inventory. The output is
+// JSON, which is also valid YAML, so it can be saved as `hosts.yml` directly.
+// Each alias becomes a host (the alias name is used to reach it) with its MAC
+// and interface available as `wol_mac` and `wol_iface` host variables, and its
+// metadata as further host variables. Metadata keys which are not valid Ansible
+// variable names, or which clash with `wol_mac` and `wol_iface`, are left out.
+// Each tag becomes a group.
+func ansibleInventory(aliases map[string]MacIface) ([]byte, error) {
+	all := ansibleGroup{Hosts: map[string]map[string]string{}}
+	for alias, mi := range aliases {
+		vars := map[string]string{}
+		for k, v := range mi.Fields {
+			if k != tagsField && reAnsibleVar.MatchString(k) && !ansibleKeywords[k] {
+				vars[k] = v
+			}
+		}
+
+		// The reserved variables are written last, so metadata can not
+		// replace them.
+		vars["wol_mac"] = mi.Mac
+		delete(vars, "wol_iface")
+		if mi.Iface != "" {
+			vars["wol_iface"] = mi.Iface
+		}
+		all.Hosts[alias] = vars
+
+		for _, tag := range mi.Tags() {
+			if all.Children == nil {
+				all.Children = map[string]ansibleGroup{}
+			}
+			group := all.Children[tag]
+			if group.Hosts == nil {
+				group.Hosts = map[string]map[string]string{}
+			}
+			group.Hosts[alias] = map[string]string{}
+			all.Children[tag] = group
+		}
+	}
+	return json.MarshalIndent(map[string]ansibleGroup{"all": all}, "", "  ")
+}
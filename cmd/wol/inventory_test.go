@@ -0,0 +1,58 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestAnsibleInventory(t *testing.T) {
+	bs, err := ansibleInventory(map[string]MacIface{
+		"nas":  {Mac: "00:11:22:33:44:55", Iface: "eth0"},
+		"htpc": {Mac: "00:11:22:33:44:66", Iface: ""},
+	})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"all": {"hosts": {
+		"htpc": {"wol_mac": "00:11:22:33:44:66"},
+		"nas":  {"wol_mac": "00:11:22:33:44:55", "wol_iface": "eth0"}
+	}}}`, string(bs))
+
+	// Metadata becomes host variables, and tags become groups.
+	bs, err = ansibleInventory(map[string]MacIface{
+		"nas": {Mac: "00:11:22:33:44:55", Fields: map[string]string{
+			"location": "basement",
+			"tags":     "storage,lab",
+		}},
+	})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"all": {
+		"hosts": {"nas": {"wol_mac": "00:11:22:33:44:55", "location": "basement"}},
+		"children": {
+			"storage": {"hosts": {"nas": {}}},
+			"lab":     {"hosts": {"nas": {}}}
+		}
+	}}`, string(bs))
+}
+
+// Metadata can not replace the reserved variables, and keys Ansible would
+// reject are left out.
+func TestAnsibleInventoryReservedAndInvalidVars(t *testing.T) {
+	bs, err := ansibleInventory(map[string]MacIface{
+		"nas": {Mac: "00:11:22:33:44:55", Fields: map[string]string{
+			"wol_mac":   "ff:ff:ff:ff:ff:ff",
+			"wol_iface": "eth9",
+			"rack-unit": "12",
+			"1st":       "yes",
+			"class":     "storage",
+			"owner":     "sam",
+		}},
+	})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"all": {"hosts": {
+		"nas": {"wol_mac": "00:11:22:33:44:55", "owner": "sam"}
+	}}}`, string(bs))
+}
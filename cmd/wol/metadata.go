@@ -0,0 +1,80 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+var (
+	reFieldKey = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// isAssignment returns true if every arg is of the form `key=value`.
+func isAssignment(args []string) bool {
+	for _, arg := range args {
+		if !strings.Contains(arg, "=") {
+			return false
+		}
+	}
+	return len(args) > 0
+}
+
+// parseAssignments parses `key=value` pairs. An empty value means the key is
+// to be removed.
+func parseAssignments(args []string) (map[string]string, error) {
+	fields := map[string]string{}
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 || !reFieldKey.MatchString(kv[0]) {
+			return nil, &cliError{Code: codeInvalidArgument, Param: "metadata",
+				Message: fmt.Sprintf("invalid metadata (%s), expected key=value", arg)}
+		}
+		fields[kv[0]] = kv[1]
+	}
+	return fields, nil
+}
+
+// formatFields renders an entry's metadata as sorted `key=value` pairs.
+func formatFields(mi MacIface) string {
+	keys := make([]string, 0, len(mi.Fields))
+	for k := range mi.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+mi.Fields[k])
+	}
+	return strings.Join(pairs, " ")
+}
+
+// Run the alias set command.
+func aliasSetCmd(alias string, assignments []string, aliases *Aliases) error {
+	fields, err := parseAssignments(assignments)
+	if err != nil {
+		return err
+	}
+
+	return aliases.Update(alias, func(mi *MacIface) error {
+		if mi.Fields == nil {
+			mi.Fields = map[string]string{}
+		}
+		for k, v := range fields {
+			if v == "" {
+				delete(mi.Fields, k)
+			} else {
+				mi.Fields[k] = v
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,37 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestParseAssignments(t *testing.T) {
+	fields, err := parseAssignments([]string{"location=basement", "owner=sam", "note=a=b", "old="})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"location": "basement",
+		"owner":    "sam",
+		"note":     "a=b",
+		"old":      "",
+	}, fields)
+
+	for _, bad := range []string{"=value", "no value", "bad key=1"} {
+		_, err := parseAssignments([]string{bad})
+		assert.NotNil(t, err, bad)
+	}
+
+	assert.True(t, isAssignment([]string{"a=1", "b="}))
+	assert.False(t, isAssignment([]string{"a=1", "eth0"}))
+	assert.False(t, isAssignment(nil))
+}
+
+func TestFormatFields(t *testing.T) {
+	mi := MacIface{Fields: map[string]string{"owner": "sam", "location": "basement"}}
+	assert.Equal(t, "location=basement owner=sam", formatFields(mi))
+	assert.Equal(t, "", formatFields(MacIface{}))
+}
@@ -0,0 +1,239 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// fetchInventory loads the name -> MacIface inventory from an external source
+// of truth. Supported sources are `csv` (a local file or http(s) URL with
+// `name,mac[,iface]` rows) and `netbox`.
+func fetchInventory(ctx context.Context, source, url, token string) (map[string]MacIface, error) {
+	if url == "" {
+		return nil, usageError("url", "reconcile requires a --url for the source")
+	}
+
+	switch strings.ToLower(source) {
+	case "csv":
+		rc, err := openURL(ctx, url, "")
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return parseInventoryCSV(rc)
+	case "netbox":
+		return fetchNetBoxInventory(ctx, url, token)
+	}
+	return nil, usageError("source", fmt.Sprintf("unknown reconcile source (%s), expected csv or netbox", source))
+}
+
+// openURL returns a reader for a local file or an http(s) URL. If a token is
+// specified, it is sent using the NetBox style `Authorization` header.
+func openURL(ctx context.Context, url, token string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return os.Open(url)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Token "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s returned %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// parseInventoryCSV reads `name,mac[,iface]` rows. Blank lines, comments and
+// a leading header row are skipped.
+func parseInventoryCSV(r io.Reader) (map[string]MacIface, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	inventory := map[string]MacIface{}
+	for idx, record := range records {
+		if len(record) < 2 {
+			return nil, fmt.Errorf("csv line %d: expected name,mac[,iface]", idx+1)
+		}
+		if idx == 0 && strings.EqualFold(record[0], "name") {
+			continue
+		}
+
+		entry := MacIface{Mac: record[1]}
+		if len(record) > 2 {
+			entry.Iface = record[2]
+		}
+		inventory[record[0]] = entry
+	}
+	return inventory, nil
+}
+
+// fetchNetBoxInventory walks the NetBox interface list and maps each device
+// name to the first interface on it which has a MAC address.
+func fetchNetBoxInventory(ctx context.Context, baseURL, token string) (map[string]MacIface, error) {
+	type netboxPage struct {
+		Next    *string `json:"next"`
+		Results []struct {
+			MacAddress *string `json:"mac_address"`
+			Device     struct {
+				Name string `json:"name"`
+			} `json:"device"`
+		} `json:"results"`
+	}
+
+	inventory := map[string]MacIface{}
+	next := strings.TrimSuffix(baseURL, "/") + "/api/dcim/interfaces/?mac_address__empty=false&limit=1000"
+	for next != "" {
+		rc, err := openURL(ctx, next, token)
+		if err != nil {
+			return nil, err
+		}
+
+		var page netboxPage
+		err = json.NewDecoder(rc).Decode(&page)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, result := range page.Results {
+			name := result.Device.Name
+			if name == "" || result.MacAddress == nil || *result.MacAddress == "" {
+				continue
+			}
+			if _, ok := inventory[name]; !ok {
+				inventory[name] = MacIface{Mac: *result.MacAddress}
+			}
+		}
+
+		next = ""
+		if page.Next != nil && *page.Next != "" {
+			// The token is sent with every page, so only follow links back
+			// to the server we were pointed at.
+			if !sameOrigin(*page.Next, baseURL) {
+				return nil, fmt.Errorf("netbox returned a next page on another server (%s)", *page.Next)
+			}
+			next = *page.Next
+		}
+	}
+	return inventory, nil
+}
+
+// sameOrigin returns true if both URLs have the same scheme and host.
+func sameOrigin(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(ua.Scheme, ub.Scheme) && strings.EqualFold(ua.Host, ub.Host)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// reconcileReport describes how the alias db differs from the inventory.
+type reconcileReport struct {
+	Missing    []string // In the inventory, but not in the alias db.
+	Mismatched []string // In both, but with different MAC addresses.
+	Unknown    []string // In the alias db, but not in the inventory.
+}
+
+// reconcile diffs the alias db against the inventory.
+func reconcile(db, inventory map[string]MacIface) reconcileReport {
+	var report reconcileReport
+	for name, entry := range inventory {
+		if existing, ok := db[name]; !ok {
+			report.Missing = append(report.Missing, name)
+		} else if normalizeMAC(existing.Mac) != normalizeMAC(entry.Mac) {
+			report.Mismatched = append(report.Mismatched, name)
+		}
+	}
+	for name := range db {
+		if _, ok := inventory[name]; !ok {
+			report.Unknown = append(report.Unknown, name)
+		}
+	}
+
+	sort.Strings(report.Missing)
+	sort.Strings(report.Mismatched)
+	sort.Strings(report.Unknown)
+	return report
+}
+
+// Run the reconcile command.
+func reconcileCmd(ctx context.Context, args []string, aliases *Aliases) error {
+	inventory, err := fetchInventory(ctx, cliFlags.ReconcileSource, cliFlags.ReconcileURL, cliFlags.ReconcileToken)
+	if err != nil {
+		return err
+	}
+
+	db, err := aliases.List()
+	if err != nil {
+		return err
+	}
+
+	report := reconcile(db, inventory)
+	for _, name := range report.Missing {
+		fmt.Printf("    missing    %s - %s\n", name, inventory[name].Mac)
+	}
+	for _, name := range report.Mismatched {
+		fmt.Printf("    mismatch   %s - %s (source has %s)\n", name, db[name].Mac, inventory[name].Mac)
+	}
+	for _, name := range report.Unknown {
+		fmt.Printf("    unknown    %s - %s\n", name, db[name].Mac)
+	}
+	fmt.Printf("%d missing, %d mismatched, %d not in source\n",
+		len(report.Missing), len(report.Mismatched), len(report.Unknown))
+
+	if cliFlags.ReconcileAdd {
+		// Check every MAC first, so a bad row does not leave half the
+		// missing aliases added.
+		for _, name := range report.Missing {
+			if _, err := net.ParseMAC(inventory[name].Mac); err != nil {
+				return &cliError{Code: codeInvalidMAC, Param: "mac", err: err,
+					Message: fmt.Sprintf("source has an invalid mac for %s: %v", name, err)}
+			}
+		}
+		for _, name := range report.Missing {
+			entry := inventory[name]
+			if err := aliases.Add(name, entry.Mac, entry.Iface); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("Added %d missing aliases\n", len(report.Missing))
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestParseInventoryCSV(t *testing.T) {
+	input := `name,mac,iface
+# Comments are ignored.
+nas, 00:11:22:33:44:55
+htpc,00-11-22-33-44-66,eth1
+`
+	inventory, err := parseInventoryCSV(strings.NewReader(input))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(inventory))
+	assert.Equal(t, MacIface{Mac: "00:11:22:33:44:55", Iface: ""}, inventory["nas"])
+	assert.Equal(t, MacIface{Mac: "00-11-22-33-44-66", Iface: "eth1"}, inventory["htpc"])
+
+	_, err = parseInventoryCSV(strings.NewReader("onlyname\n"))
+	assert.NotNil(t, err)
+}
+
+func TestReconcile(t *testing.T) {
+	db := map[string]MacIface{
+		"nas":  {Mac: "00:11:22:33:44:55", Iface: ""},
+		"htpc": {Mac: "00:11:22:33:44:66", Iface: ""},
+		"old":  {Mac: "00:11:22:33:44:77", Iface: ""},
+	}
+	inventory := map[string]MacIface{
+		"nas":  {Mac: "00-11-22-33-44-55", Iface: ""},
+		"htpc": {Mac: "00:11:22:33:44:99", Iface: ""},
+		"new":  {Mac: "00:11:22:33:44:88", Iface: ""},
+	}
+
+	report := reconcile(db, inventory)
+	assert.Equal(t, []string{"new"}, report.Missing)
+	assert.Equal(t, []string{"htpc"}, report.Mismatched)
+	assert.Equal(t, []string{"old"}, report.Unknown)
+}
+
+func TestSameOrigin(t *testing.T) {
+	assert.True(t, sameOrigin("https://netbox.lan/api/dcim/interfaces/?offset=1000", "https://netbox.lan"))
+	assert.True(t, sameOrigin("https://NetBox.lan/api/", "https://netbox.lan/"))
+	assert.False(t, sameOrigin("http://netbox.lan/api/", "https://netbox.lan"))
+	assert.False(t, sameOrigin("https://evil.example/api/", "https://netbox.lan"))
+	assert.False(t, sameOrigin("https://netbox.lan:8443/api/", "https://netbox.lan"))
+}
+
+func TestFetchNetBoxInventoryNext(t *testing.T) {
+	var next string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Token secret", r.Header.Get("Authorization"))
+		fmt.Fprintf(w, `{"next": %q, "results": [{"mac_address": "00:11:22:33:44:55", "device": {"name": "nas"}}]}`, next)
+	}))
+	defer srv.Close()
+
+	inventory, err := fetchNetBoxInventory(context.Background(), srv.URL, "secret")
+	assert.Nil(t, err)
+	assert.Equal(t, MacIface{Mac: "00:11:22:33:44:55"}, inventory["nas"])
+
+	// The token must not be sent to another server.
+	next = "http://evil.example/api/dcim/interfaces/?offset=1000"
+	_, err = fetchNetBoxInventory(context.Background(), srv.URL, "secret")
+	assert.NotNil(t, err)
+}
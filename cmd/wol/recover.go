@@ -0,0 +1,193 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// corruptDBError is returned when the alias db exists but can not be opened
+// or fails its integrity check.
+type corruptDBError struct {
+	path string
+	err  error
+}
+
+func (e *corruptDBError) Error() string {
+	return fmt.Sprintf("alias db %s is corrupt: %v", e.path, e.err)
+}
+
+func (e *corruptDBError) Unwrap() error {
+	return e.err
+}
+
+// openBolt opens the bolt db at `dbpath` and reads every key in it. Failures
+// caused by a damaged file (including panics from bolt while reading it) are
+// reported as a *corruptDBError. The full consistency check (see checkBolt) is
+// left to recovery, since it is slow on big dbs and bolt runs it on its own
+// goroutine, where a panic can not be recovered.
+func openBolt(dbpath string, options *bolt.Options) (db *bolt.DB, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if db != nil {
+				db.Close()
+			}
+			db, err = nil, &corruptDBError{dbpath, fmt.Errorf("%v", r)}
+		}
+	}()
+
+	db, err = bolt.Open(dbpath, 0660, options)
+	switch {
+	case err == nil:
+	case err == bolt.ErrTimeout, os.IsNotExist(err), os.IsPermission(err):
+		return nil, err
+	default:
+		return nil, &corruptDBError{dbpath, err}
+	}
+
+	if err := db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(_ []byte, b *bolt.Bucket) error {
+			return b.ForEach(func(_, _ []byte) error { return nil })
+		})
+	}); err != nil {
+		db.Close()
+		return nil, &corruptDBError{dbpath, err}
+	}
+	return db, nil
+}
+
+// checkBolt runs bolt's consistency check on `db`, and returns all of the
+// problems it found.
+func checkBolt(db *bolt.DB) error {
+	var errs []error
+	err := db.View(func(tx *bolt.Tx) error {
+		// Drain the channel, the check goroutine blocks until every error
+		// has been read.
+		for cerr := range tx.Check() {
+			errs = append(errs, cerr)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// salvageBucket copies all readable key/value pairs of the top level bucket
+// `name` from `src` into `dst`. Any panic while reading the damaged bucket is
+// turned into an error so that the remaining buckets can still be salvaged.
+func salvageBucket(src *bolt.DB, dst *bolt.Tx, name []byte) (count int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	out, err := dst.CreateBucketIfNotExists(name)
+	if err != nil {
+		return 0, err
+	}
+	err = src.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(name).ForEach(func(k, v []byte) error {
+			// Nested buckets are not used by the alias db.
+			if v == nil {
+				return nil
+			}
+			count++
+			return out.Put(k, v)
+		})
+	})
+	return count, err
+}
+
+// recoverAliasDB salvages what it can from the damaged db at `dbpath` into a
+// fresh db, and moves the damaged file aside to `<dbpath>.corrupt-<time>`. The
+// `Meta` bucket is deliberately not salvaged, so that the recovered db is run
+// through all schema migrations when it is next loaded.
+func recoverAliasDB(dbpath string, timeout time.Duration) error {
+	freshPath := dbpath + ".recovering"
+	os.Remove(freshPath)
+
+	fresh, err := bolt.Open(freshPath, 0660, nil)
+	if err != nil {
+		return err
+	}
+
+	// The damaged db may not even open, in which case nothing is salvaged.
+	src, err := func() (db *bolt.DB, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				db, err = nil, fmt.Errorf("%v", r)
+			}
+		}()
+		return bolt.Open(dbpath, 0660, &bolt.Options{ReadOnly: true, Timeout: timeout})
+	}()
+	if err == bolt.ErrTimeout {
+		// A locked db is in use, not corrupt. Leave it alone.
+		fresh.Close()
+		os.Remove(freshPath)
+		return &lockedDBError{dbpath, lockOwner(dbpath)}
+	}
+	if err != nil {
+		fmt.Printf("Unable to read %s (%v), nothing can be salvaged\n", dbpath, err)
+	} else {
+		if cerr := checkBolt(src); cerr != nil {
+			fmt.Printf("Consistency check of %s failed:\n", dbpath)
+			for _, line := range strings.Split(cerr.Error(), "\n") {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+
+		var names [][]byte
+		func() {
+			defer func() { recover() }()
+			src.View(func(tx *bolt.Tx) error {
+				return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+					names = append(names, append([]byte(nil), name...))
+					return nil
+				})
+			})
+		}()
+
+		for _, name := range names {
+			if string(name) == metaBucketName {
+				continue
+			}
+			var count int
+			serr := fresh.Update(func(tx *bolt.Tx) error {
+				var err error
+				count, err = salvageBucket(src, tx, name)
+				return err
+			})
+			if serr != nil {
+				fmt.Printf("    bucket %s: unreadable (%v)\n", name, serr)
+			} else {
+				fmt.Printf("    bucket %s: salvaged %d entries\n", name, count)
+			}
+		}
+		src.Close()
+	}
+
+	if err := fresh.Close(); err != nil {
+		return err
+	}
+
+	quarantine := fmt.Sprintf("%s.corrupt-%s", dbpath, time.Now().Format("20060102-150405"))
+	if err := os.Rename(dbpath, quarantine); err != nil {
+		return err
+	}
+	if err := os.Rename(freshPath, dbpath); err != nil {
+		return err
+	}
+	fmt.Printf("Recovered alias db %s, the damaged file was moved to %s\n", dbpath, quarantine)
+	return nil
+}
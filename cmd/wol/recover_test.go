@@ -0,0 +1,70 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// A garbage file is reported as corrupt, and recovering it yields an empty but
+// usable db while keeping the original around.
+func TestRecoverGarbageDB(t *testing.T) {
+	dir := t.TempDir()
+	dbpath := filepath.Join(dir, "bolt.db")
+	assert.Nil(t, os.WriteFile(dbpath, []byte("this is not a bolt db"), 0660))
+
+	_, err := LoadAliases(dbpath)
+	var cerr *corruptDBError
+	assert.True(t, errors.As(err, &cerr))
+
+	assert.Nil(t, recoverAliasDB(dbpath, 0))
+
+	aliases, err := LoadAliases(dbpath)
+	assert.Nil(t, err)
+	defer aliases.Close()
+	list, err := aliases.List()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(list))
+
+	quarantined, err := filepath.Glob(dbpath + ".corrupt-*")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(quarantined))
+}
+
+// Readable buckets are carried over into the recovered db.
+func TestRecoverSalvagesAliases(t *testing.T) {
+	dbpath := filepath.Join(t.TempDir(), "bolt.db")
+
+	aliases, err := LoadAliases(dbpath)
+	assert.Nil(t, err)
+	assert.Nil(t, aliases.Add("one", "00:11:22:33:44:55", "eth0"))
+	assert.Nil(t, aliases.Close())
+
+	assert.Nil(t, recoverAliasDB(dbpath, 0))
+
+	aliases, err = LoadAliases(dbpath)
+	assert.Nil(t, err)
+	defer aliases.Close()
+	mi, err := aliases.Get("one")
+	assert.Nil(t, err)
+	assert.Equal(t, "00:11:22:33:44:55", mi.Mac)
+	assert.Equal(t, "eth0", mi.Iface)
+}
+
+// A healthy db passes the full consistency check.
+func TestCheckBolt(t *testing.T) {
+	dbpath := filepath.Join(t.TempDir(), "bolt.db")
+
+	aliases, err := LoadAliases(dbpath)
+	assert.Nil(t, err)
+	defer aliases.Close()
+	assert.Nil(t, aliases.Add("one", "00:11:22:33:44:55", "eth0"))
+	assert.Nil(t, checkBolt(aliases.db))
+}
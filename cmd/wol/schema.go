@@ -0,0 +1,136 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+const (
+	metaBucketName   = "Meta"
+	schemaVersionKey = "schema_version"
+)
+
+// errSchemaTooNew is returned when the db was written by a newer binary.
+var errSchemaTooNew = errors.New("alias db schema is newer than this version of wol supports")
+
+// migration upgrades the db from one schema version to the next.
+type migration struct {
+	description string
+	apply       func(tx *bolt.Tx) error
+}
+
+// migrations[i] upgrades a db at schema version `i` to version `i+1`. Databases
+// created before schema versioning was introduced are at version 0. New
+// migrations must only ever be appended to this list. Adding a new per-alias
+// field (see MacIface.Fields) does not need a migration.
+var migrations = []migration{
+	{"create the Aliases and Wakes buckets", func(tx *bolt.Tx) error {
+		for _, name := range []string{bucketName, wakesBucketName} {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{"create the Sites bucket", func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(sitesBucketName))
+		return err
+	}},
+}
+
+// currentSchemaVersion is the schema version this binary reads and writes.
+func currentSchemaVersion() uint64 {
+	return uint64(len(migrations))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// readSchemaVersion returns the schema version stored in the db.
+func readSchemaVersion(tx *bolt.Tx) uint64 {
+	bucket := tx.Bucket([]byte(metaBucketName))
+	if bucket == nil {
+		return 0
+	}
+	value := bucket.Get([]byte(schemaVersionKey))
+	if len(value) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(value)
+}
+
+// writeSchemaVersion stores the schema version in the db.
+func writeSchemaVersion(tx *bolt.Tx, version uint64) error {
+	bucket, err := tx.CreateBucketIfNotExists([]byte(metaBucketName))
+	if err != nil {
+		return err
+	}
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, version)
+	return bucket.Put([]byte(schemaVersionKey), value)
+}
+
+// schemaBackupPath returns where a db at `version` is backed up to before it
+// is migrated.
+func schemaBackupPath(dbpath string, version uint64) string {
+	return fmt.Sprintf("%s.schema%d.bak", dbpath, version)
+}
+
+// SchemaVersion returns the schema version of the alias db.
+func (a *Aliases) SchemaVersion() (uint64, error) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	var version uint64
+	err := a.db.View(func(tx *bolt.Tx) error {
+		version = readSchemaVersion(tx)
+		return nil
+	})
+	return version, err
+}
+
+// migrateSchema brings the db at `dbpath` up to the current schema version.
+// Databases which already hold data are copied to a backup file before they
+// are migrated. All migrations are applied in a single transaction, so a
+// failed migration leaves the db untouched.
+func migrateSchema(db *bolt.DB, dbpath string) error {
+	var version uint64
+	var populated bool
+	if err := db.View(func(tx *bolt.Tx) error {
+		version = readSchemaVersion(tx)
+		populated = tx.Bucket([]byte(bucketName)) != nil
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	switch {
+	case version == currentSchemaVersion():
+		return nil
+	case version > currentSchemaVersion():
+		return errSchemaTooNew
+	}
+
+	if populated {
+		if err := db.View(func(tx *bolt.Tx) error {
+			return tx.CopyFile(schemaBackupPath(dbpath, version), 0600)
+		}); err != nil {
+			return fmt.Errorf("failed to back up alias db before migrating: %v", err)
+		}
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		for v := version; v < currentSchemaVersion(); v++ {
+			if err := migrations[v].apply(tx); err != nil {
+				return fmt.Errorf("schema migration %d (%s) failed: %v", v+1, migrations[v].description, err)
+			}
+		}
+		return writeSchemaVersion(tx, currentSchemaVersion())
+	})
+}
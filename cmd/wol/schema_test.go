@@ -0,0 +1,92 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// A new db is created at the current schema version, without a backup.
+func TestSchemaNewDB(t *testing.T) {
+	dbpath := filepath.Join(t.TempDir(), "bolt.db")
+
+	aliases, err := LoadAliases(dbpath)
+	assert.Nil(t, err)
+	defer aliases.Close()
+
+	version, err := aliases.SchemaVersion()
+	assert.Nil(t, err)
+	assert.Equal(t, currentSchemaVersion(), version)
+
+	_, err = os.Stat(schemaBackupPath(dbpath, 0))
+	assert.True(t, os.IsNotExist(err))
+}
+
+// A db created before schema versioning is backed up and migrated, keeping
+// its aliases.
+func TestSchemaMigrateLegacyDB(t *testing.T) {
+	dbpath := filepath.Join(t.TempDir(), "bolt.db")
+
+	db, err := bolt.Open(dbpath, 0660, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucket([]byte(bucketName))
+		if err != nil {
+			return err
+		}
+		buf, err := EncodeFromMacIface("00:11:22:33:44:55", "eth0")
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte("legacy"), buf.Bytes())
+	}))
+	assert.Nil(t, db.Close())
+
+	aliases, err := LoadAliases(dbpath)
+	assert.Nil(t, err)
+	defer aliases.Close()
+
+	version, err := aliases.SchemaVersion()
+	assert.Nil(t, err)
+	assert.Equal(t, currentSchemaVersion(), version)
+
+	mi, err := aliases.Get("legacy")
+	assert.Nil(t, err)
+	assert.Equal(t, "00:11:22:33:44:55", mi.Mac)
+
+	_, err = os.Stat(schemaBackupPath(dbpath, 0))
+	assert.Nil(t, err)
+}
+
+// A db written by a newer binary is opened read-only.
+func TestSchemaNewerDB(t *testing.T) {
+	dbpath := filepath.Join(t.TempDir(), "bolt.db")
+
+	db, err := bolt.Open(dbpath, 0660, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucket([]byte(bucketName)); err != nil {
+			return err
+		}
+		return writeSchemaVersion(tx, currentSchemaVersion()+1)
+	}))
+	assert.Nil(t, db.Close())
+
+	aliases, err := LoadAliases(dbpath)
+	assert.Nil(t, err)
+	defer aliases.Close()
+
+	assert.Equal(t, errReadOnly, aliases.Add("one", "00:11:22:33:44:55", ""))
+
+	version, err := aliases.SchemaVersion()
+	assert.Nil(t, err)
+	assert.Equal(t, currentSchemaVersion()+1, version)
+}
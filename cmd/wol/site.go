@@ -0,0 +1,284 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"net"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+const (
+	sitesBucketName = "Sites"
+)
+
+// siteKeys are the settings a site profile may hold. The first three provide
+// defaults for the --interface, --bcast and --port flags respectively, while
+// `subnet` is used to pick the site automatically.
+var siteKeys = map[string]bool{
+	"iface":  true,
+	"bcast":  true,
+	"port":   true,
+	"subnet": true,
+}
+
+// Site is a named set of network settings (for example the interface and
+// broadcast address used at a particular location).
+type Site map[string]string
+
+func decodeSite(value []byte) (Site, error) {
+	var site Site
+	err := gob.NewDecoder(bytes.NewBuffer(value)).Decode(&site)
+	return site, err
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// SetSite creates or updates the site profile `name`. Settings with an empty
+// value are removed, and a site without any settings left is deleted.
+func (a *Aliases) SetSite(name string, settings map[string]string) error {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	if a.readOnly {
+		return errReadOnly
+	}
+
+	return a.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(sitesBucketName))
+		site := Site{}
+		if value := bucket.Get([]byte(name)); value != nil {
+			var err error
+			if site, err = decodeSite(value); err != nil {
+				return err
+			}
+		}
+		for k, v := range settings {
+			if v == "" {
+				delete(site, k)
+			} else {
+				site[k] = v
+			}
+		}
+		if len(site) == 0 {
+			return bucket.Delete([]byte(name))
+		}
+
+		buf := bytes.NewBuffer(nil)
+		if err := gob.NewEncoder(buf).Encode(site); err != nil {
+			return err
+		}
+		return bucket.Put([]byte(name), buf.Bytes())
+	})
+}
+
+// DelSite removes the site profile `name`.
+func (a *Aliases) DelSite(name string) error {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	if a.readOnly {
+		return errReadOnly
+	}
+
+	return a.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(sitesBucketName)).Delete([]byte(name))
+	})
+}
+
+// Sites returns all site profiles.
+func (a *Aliases) Sites() (map[string]Site, error) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	sites := map[string]Site{}
+	err := a.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(sitesBucketName))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			site, err := decodeSite(v)
+			if err == nil {
+				sites[string(k)] = site
+			}
+			return err
+		})
+	})
+	return sites, err
+}
+
+// GetSite returns the site profile `name`.
+func (a *Aliases) GetSite(name string) (Site, error) {
+	sites, err := a.Sites()
+	if err != nil {
+		return nil, err
+	}
+	site, ok := sites[name]
+	if !ok {
+		return nil, usageError("site", fmt.Sprintf("site (%s) not found in db", name))
+	}
+	return site, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// siteIface is the interface of the site in use. Unlike the broadcast address
+// and port, it is only a default for aliases which do not have an interface
+// of their own (see wakeTarget).
+var siteIface string
+
+// applySite uses the settings of `site` for any of the broadcast address and
+// port which were not given on the command line, and remembers its interface.
+func applySite(name string, site Site) {
+	siteIface = site["iface"]
+	if !flagSet("bcast") && site["bcast"] != "" {
+		cliFlags.BroadcastIP = site["bcast"]
+	}
+	if !flagSet("port") && site["port"] != "" {
+		cliFlags.UDPPort = site["port"]
+	}
+	tracef("using site %s: interface %q, broadcast %s, port %s",
+		name, siteIface, cliFlags.BroadcastIP, cliFlags.UDPPort)
+}
+
+// detectSite returns the site whose `subnet` contains one of `addrs`. When the
+// subnets of several sites match, the most specific (longest prefix) one is
+// picked. Nothing is returned when no site matches, or when the best matches
+// are equally specific.
+func detectSite(sites map[string]Site, addrs []net.IP) (string, bool) {
+	var matches []string
+	best := -1
+	for name, site := range sites {
+		_, subnet, err := net.ParseCIDR(site["subnet"])
+		if err != nil {
+			continue
+		}
+		for _, ip := range addrs {
+			if !subnet.Contains(ip) {
+				continue
+			}
+			switch ones, _ := subnet.Mask.Size(); {
+			case ones > best:
+				best, matches = ones, []string{name}
+			case ones == best:
+				matches = append(matches, name)
+			}
+			break
+		}
+	}
+	if len(matches) > 1 {
+		sort.Strings(matches)
+		tracef("sites %v all match the connected networks equally well, not picking one", matches)
+	}
+	if len(matches) != 1 {
+		return "", false
+	}
+	return matches[0], true
+}
+
+// localAddrs returns the addresses of all interfaces which are up.
+func localAddrs() []net.IP {
+	var ips []net.IP
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipnet, ok := addr.(*net.IPNet); ok {
+				ips = append(ips, ipnet.IP)
+			}
+		}
+	}
+	return ips
+}
+
+// useSite applies the `--site` profile. Without `--site`, the site whose
+// subnet matches a connected network is used, if there is exactly one.
+func useSite(aliases *Aliases) error {
+	if cliFlags.Site == "" {
+		sites, err := aliases.Sites()
+		if err != nil || len(sites) == 0 {
+			return err
+		}
+		if name, ok := detectSite(sites, localAddrs()); ok {
+			tracef("detected site %s from the connected networks", name)
+			applySite(name, sites[name])
+		}
+		return nil
+	}
+	site, err := aliases.GetSite(cliFlags.Site)
+	if err != nil {
+		return err
+	}
+	applySite(cliFlags.Site, site)
+	return nil
+}
+
+// formatSite renders a site's settings as sorted `key=value` pairs.
+func formatSite(site Site) string {
+	return formatFields(MacIface{Fields: site})
+}
+
+// Run the site command.
+func siteCmd(ctx context.Context, args []string, aliases *Aliases) error {
+	sub := "list"
+	if len(args) > 0 {
+		sub = args[0]
+	}
+
+	switch {
+	case sub == "list" && len(args) <= 1:
+		sites, err := aliases.Sites()
+		if err != nil {
+			return err
+		}
+		if len(sites) == 0 {
+			fmt.Printf("No sites found! Add one with \"wol site add <name> iface=<interface> bcast=<ip>\"\n")
+			return nil
+		}
+		names := make([]string, 0, len(sites))
+		for name := range sites {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("    %s - %s\n", name, formatSite(sites[name]))
+		}
+		return nil
+
+	case sub == "add" && len(args) >= 3:
+		settings, err := parseAssignments(args[2:])
+		if err != nil {
+			return err
+		}
+		for k, v := range settings {
+			if !siteKeys[k] {
+				return usageError("site", fmt.Sprintf("unknown site setting (%s), expected iface, bcast, port or subnet", k))
+			}
+			if _, _, err := net.ParseCIDR(v); k == "subnet" && v != "" && err != nil {
+				return usageError("site", fmt.Sprintf("invalid subnet (%s), expected a CIDR such as 10.1.0.0/24", v))
+			}
+		}
+		return aliases.SetSite(args[1], settings)
+
+	case sub == "remove" && len(args) == 2:
+		return aliases.DelSite(args[1])
+	}
+	return usageError("site", "site command expects list, add <name> <key=value>... or remove <name>")
+}
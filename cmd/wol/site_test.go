@@ -0,0 +1,87 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestSites(t *testing.T) {
+	aliases, err := LoadAliases(filepath.Join(t.TempDir(), "bolt.db"))
+	assert.Nil(t, err)
+	defer aliases.Close()
+
+	assert.Nil(t, aliases.SetSite("home", map[string]string{"iface": "wlan0", "bcast": "192.168.0.255"}))
+	assert.Nil(t, aliases.SetSite("office", map[string]string{"iface": "eth0", "bcast": "10.1.0.255", "port": "7"}))
+	assert.Nil(t, aliases.SetSite("office", map[string]string{"port": ""}))
+
+	site, err := aliases.GetSite("office")
+	assert.Nil(t, err)
+	assert.Equal(t, Site{"iface": "eth0", "bcast": "10.1.0.255"}, site)
+
+	sites, err := aliases.Sites()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(sites))
+
+	assert.Nil(t, aliases.DelSite("home"))
+	_, err = aliases.GetSite("home")
+	assert.NotNil(t, err)
+}
+
+// Flags given on the command line take precedence over the site profile.
+func TestApplySite(t *testing.T) {
+	saved, savedParser, savedIface := cliFlags, cliParser, siteIface
+	defer func() { cliFlags, cliParser, siteIface = saved, savedParser, savedIface }()
+
+	cliParser = newCLIParser()
+	_, err := cliParser.ParseArgs([]string{"-p", "7"})
+	assert.Nil(t, err)
+	applySite("office", Site{"iface": "eth0", "bcast": "10.1.0.255", "port": "9"})
+	assert.Equal(t, "eth0", siteIface)
+	assert.Equal(t, "", cliFlags.BroadcastInterface)
+	assert.Equal(t, "10.1.0.255", cliFlags.BroadcastIP)
+	assert.Equal(t, "7", cliFlags.UDPPort)
+
+	// Even when they match the defaults.
+	cliParser = newCLIParser()
+	_, err = cliParser.ParseArgs([]string{"-b", "255.255.255.255", "-p", "9"})
+	assert.Nil(t, err)
+	applySite("office", Site{"bcast": "10.1.0.255", "port": "7"})
+	assert.Equal(t, "255.255.255.255", cliFlags.BroadcastIP)
+	assert.Equal(t, "9", cliFlags.UDPPort)
+}
+
+func TestDetectSite(t *testing.T) {
+	sites := map[string]Site{
+		"home":   {"iface": "wlan0", "subnet": "192.168.0.0/24"},
+		"office": {"iface": "eth0", "subnet": "10.1.0.0/16"},
+		"lab":    {"iface": "eth1", "subnet": "10.1.2.0/24"},
+		"vpn":    {"iface": "tun0"},
+	}
+
+	name, ok := detectSite(sites, []net.IP{net.ParseIP("172.17.0.1"), net.ParseIP("192.168.0.12")})
+	assert.True(t, ok)
+	assert.Equal(t, "home", name)
+
+	// Of overlapping subnets, the most specific one wins.
+	name, ok = detectSite(sites, []net.IP{net.ParseIP("10.1.2.3")})
+	assert.True(t, ok)
+	assert.Equal(t, "lab", name)
+	name, ok = detectSite(sites, []net.IP{net.ParseIP("10.1.3.3")})
+	assert.True(t, ok)
+	assert.Equal(t, "office", name)
+
+	// Equally specific matches are ambiguous.
+	sites["lab2"] = Site{"iface": "eth2", "subnet": "10.1.2.0/24"}
+	_, ok = detectSite(sites, []net.IP{net.ParseIP("10.1.2.3")})
+	assert.False(t, ok)
+
+	_, ok = detectSite(sites, []net.IP{net.ParseIP("172.17.0.1")})
+	assert.False(t, ok)
+}
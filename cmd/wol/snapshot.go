@@ -0,0 +1,78 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+const (
+	snapshotDirName = "snapshots"
+	snapshotSuffix  = ".snap"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// snapshotDir returns the directory snapshots of the db at `dbpath` go in.
+func snapshotDir(dbpath string) string {
+	return filepath.Join(filepath.Dir(dbpath), snapshotDirName)
+}
+
+// Snapshot writes a consistent copy of the alias db into `dir` and then removes
+// all but the `keep` most recent snapshots. A `keep` of zero keeps them all.
+// The path of the new snapshot is returned.
+func (a *Aliases) Snapshot(dir string, keep int) (string, error) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+
+	prefix := filepath.Base(a.path) + "."
+	path := filepath.Join(dir, prefix+time.Now().Format("20060102-150405.000000000")+snapshotSuffix)
+	if err := a.db.View(func(tx *bolt.Tx) error {
+		return tx.CopyFile(path, 0600)
+	}); err != nil {
+		return "", err
+	}
+
+	if keep > 0 {
+		// The timestamp format sorts lexically, oldest first.
+		snapshots, err := filepath.Glob(filepath.Join(dir, prefix+"*"+snapshotSuffix))
+		if err != nil {
+			return path, err
+		}
+		sort.Strings(snapshots)
+		for len(snapshots) > keep {
+			if err := os.Remove(snapshots[0]); err != nil {
+				return path, err
+			}
+			snapshots = snapshots[1:]
+		}
+	}
+	return path, nil
+}
+
+// Run the db command.
+func dbCmd(ctx context.Context, args []string, aliases *Aliases) error {
+	if len(args) == 0 || args[0] != "snapshot" {
+		return usageError("", "db command requires a subcommand (snapshot)")
+	}
+
+	path, err := aliases.Snapshot(snapshotDir(aliases.path), cliFlags.Keep)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Snapshot written to %s\n", path)
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Snapshots are rotated, keeping only the most recent ones.
+func TestSnapshotRotation(t *testing.T) {
+	dir := t.TempDir()
+	aliases, err := LoadAliases(filepath.Join(dir, "bolt.db"))
+	assert.Nil(t, err)
+	defer aliases.Close()
+
+	var paths []string
+	for i := 0; i < 3; i++ {
+		path, err := aliases.Snapshot(snapshotDir(aliases.path), 2)
+		assert.Nil(t, err)
+		paths = append(paths, path)
+	}
+
+	snapshots, err := filepath.Glob(filepath.Join(snapshotDir(aliases.path), "*"))
+	assert.Nil(t, err)
+	assert.Equal(t, paths[1:], snapshots)
+
+	// Snapshots are dbs in their own right.
+	snapshot, err := LoadAliasesWithOptions(paths[2], AliasOptions{ReadOnly: true})
+	assert.Nil(t, err)
+	assert.Nil(t, snapshot.Close())
+}
@@ -0,0 +1,91 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+const (
+	resolverPrefix = "wol-resolve-"
+)
+
+var (
+	reTargetScheme = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9_-]*):(.+)$`)
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// splitTarget splits a `scheme:name` target (eg. `netbox:webserver01`) into its
+// parts. MAC addresses (which also contain colons) are never split.
+func splitTarget(target string) (scheme, name string, ok bool) {
+	if _, err := net.ParseMAC(target); err == nil {
+		return "", "", false
+	}
+
+	m := reTargetScheme.FindStringSubmatch(target)
+	if m == nil {
+		return "", "", false
+	}
+	return strings.ToLower(m[1]), m[2], true
+}
+
+// runResolver resolves `name` using the external `wol-resolve-<scheme>` command
+// found in the PATH. The command is passed the name as its only argument and
+// is expected to print the MAC address (optionally followed by an interface)
+// to stdout.
+func runResolver(ctx context.Context, scheme, name string) (MacIface, error) {
+	path, err := exec.LookPath(resolverPrefix + scheme)
+	if err != nil {
+		return MacIface{}, fmt.Errorf("no resolver found for %s: targets (expected %s%s in PATH)",
+			scheme, resolverPrefix, scheme)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, name)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return MacIface{}, fmt.Errorf("resolver %s failed for %s: %v %s",
+			scheme, name, err, strings.TrimSpace(stderr.String()))
+	}
+
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return MacIface{}, fmt.Errorf("resolver %s returned nothing for %s", scheme, name)
+	}
+
+	entry := MacIface{Mac: fields[0]}
+	if len(fields) > 1 {
+		entry.Iface = fields[1]
+	}
+	return entry, nil
+}
+
+// lookupTarget converts a user supplied target into a MacIface. The target is
+// first checked against the aliases, then against any external resolvers (for
+// `scheme:name` targets), and is otherwise assumed to be a MAC address.
+func lookupTarget(ctx context.Context, target string, aliases *Aliases) (MacIface, error) {
+	if mi, err := aliases.Get(target); err == nil {
+		tracef("target %s is an alias for MAC %s (interface %q)", target, mi.Mac, mi.Iface)
+		return mi, nil
+	}
+	if scheme, name, ok := splitTarget(target); ok {
+		mi, err := runResolver(ctx, scheme, name)
+		if err == nil {
+			tracef("target %s resolved by %s%s to MAC %s (interface %q)",
+				target, resolverPrefix, scheme, mi.Mac, mi.Iface)
+		}
+		return mi, err
+	}
+	tracef("target %s is not an alias, using it as a MAC address", target)
+	return MacIface{Mac: target}, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestSplitTarget(t *testing.T) {
+	for _, tc := range []struct {
+		target, scheme, name string
+		ok                   bool
+	}{
+		{"netbox:webserver01", "netbox", "webserver01", true},
+		{"DNS:host.example.com", "dns", "host.example.com", true},
+		{"aa:bb:cc:dd:ee:ff", "", "", false},
+		{"00:11:22:33:44:55", "", "", false},
+		{"00-11-22-33-44-55", "", "", false},
+		{"skynet", "", "", false},
+		{":name", "", "", false},
+	} {
+		scheme, name, ok := splitTarget(tc.target)
+		assert.Equal(t, tc.ok, ok, tc.target)
+		assert.Equal(t, tc.scheme, scheme, tc.target)
+		assert.Equal(t, tc.name, name, tc.target)
+	}
+}
+
+func TestRunResolver(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("resolver test uses a shell script")
+	}
+
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho \"00:11:22:33:44:55 eth1\"\n"
+	err := os.WriteFile(filepath.Join(dir, resolverPrefix+"test"), []byte(script), 0755)
+	assert.Nil(t, err)
+	t.Setenv("PATH", dir)
+
+	entry, err := runResolver(context.Background(), "test", "anything")
+	assert.Nil(t, err)
+	assert.Equal(t, MacIface{Mac: "00:11:22:33:44:55", Iface: "eth1"}, entry)
+
+	_, err = runResolver(context.Background(), "missing", "anything")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,17 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"os"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// tracef prints a decision point to stderr when `--verbose` is specified.
+func tracef(format string, args ...interface{}) {
+	if cliFlags.Verbose {
+		fmt.Fprintf(os.Stderr, "[trace] "+format+"\n", args...)
+	}
+}
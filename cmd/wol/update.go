@@ -0,0 +1,289 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+const (
+	// checksumsAsset is the release asset listing the sha256 of every other
+	// asset, in `sha256sum` format.
+	checksumsAsset = "checksums.txt"
+
+	// maxAssetSize bounds how much of a download is read into memory.
+	maxAssetSize = 64 << 20
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// platformAsset returns the release asset built for `goos`/`goarch`. Assets are
+// matched by name, for example `wol_linux_amd64`, `go-wol_2.1.0_linux_amd64.tar.gz`
+// or `wol-windows-amd64.zip`.
+func platformAsset(rel release, goos, goarch string) (releaseAsset, error) {
+	var matches []releaseAsset
+	for _, asset := range rel.Assets {
+		name := strings.ToLower(strings.NewReplacer("-", "_", ".", "_").Replace(asset.Name))
+		// The arch must be a whole token, so that `arm` does not match
+		// `arm64`.
+		if asset.Name != checksumsAsset && strings.Contains(name+"_", "_"+goos+"_"+goarch+"_") {
+			matches = append(matches, asset)
+		}
+	}
+	if len(matches) == 0 {
+		return releaseAsset{}, fmt.Errorf("release %s has no build for %s/%s", rel.TagName, goos, goarch)
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Name < matches[j].Name })
+	return matches[0], nil
+}
+
+// download fetches a release asset into memory.
+func download(ctx context.Context, asset releaseAsset) ([]byte, error) {
+	body, err := openURL(ctx, asset.URL, "")
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	bs, err := io.ReadAll(io.LimitReader(body, maxAssetSize+1))
+	if err == nil && len(bs) > maxAssetSize {
+		err = fmt.Errorf("%s is larger than %d bytes", asset.Name, maxAssetSize)
+	}
+	return bs, err
+}
+
+// verifyChecksum checks `data` against the entry for `name` in a `sha256sum`
+// style checksums file.
+func verifyChecksum(checksums []byte, name string, data []byte) error {
+	sum := sha256.Sum256(data)
+	actual := hex.EncodeToString(sum[:])
+
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != name {
+			continue
+		}
+		if !strings.EqualFold(fields[0], actual) {
+			return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, fields[0], actual)
+		}
+		return nil
+	}
+	return fmt.Errorf("no checksum listed for %s", name)
+}
+
+// extractBinary returns the wol executable from a downloaded asset, which is
+// either the binary itself or a .tar.gz / .zip archive containing it.
+func extractBinary(name string, data []byte) ([]byte, error) {
+	isWol := func(file string) bool {
+		base := path.Base(file)
+		return base == "wol" || base == "wol.exe"
+	}
+
+	switch {
+	case strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz"):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		tr := tar.NewReader(gz)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if hdr.Typeflag == tar.TypeReg && isWol(hdr.Name) {
+				return io.ReadAll(io.LimitReader(tr, maxAssetSize))
+			}
+		}
+
+	case strings.HasSuffix(name, ".zip"):
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if !isWol(f.Name) {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return io.ReadAll(io.LimitReader(rc, maxAssetSize))
+		}
+
+	default:
+		return data, nil
+	}
+	return nil, fmt.Errorf("%s does not contain a wol binary", name)
+}
+
+// replaceExecutable atomically replaces the file at `exe` with `data`. The new
+// file is written next to the old one and renamed over it, so a failed update
+// never leaves a partial binary behind.
+func replaceExecutable(exe string, data []byte) error {
+	fi, err := os.Stat(exe)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".wol-update-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), fi.Mode().Perm()); err != nil {
+		return err
+	}
+
+	// Windows does not allow a running executable to be replaced, but does
+	// allow it to be renamed out of the way.
+	if runtime.GOOS == "windows" {
+		old := exe + ".old"
+		os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp.Name(), exe); err != nil {
+			// Put the old binary back, rather than leaving nothing at `exe`.
+			if rerr := os.Rename(old, exe); rerr != nil {
+				return fmt.Errorf("%v (and restoring %s failed: %v)", err, old, rerr)
+			}
+			return err
+		}
+		return nil
+	}
+	return os.Rename(tmp.Name(), exe)
+}
+
+// selfUpdate downloads the release described by `check` and installs it over
+// the running executable.
+func selfUpdate(ctx context.Context, check updateCheck) error {
+	asset, err := platformAsset(check.release, runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return err
+	}
+
+	var checksums []byte
+	for _, a := range check.release.Assets {
+		if a.Name == checksumsAsset {
+			if checksums, err = download(ctx, a); err != nil {
+				return err
+			}
+		}
+	}
+	if checksums == nil {
+		return fmt.Errorf("release %s has no %s, refusing to install an unverified binary", check.release.TagName, checksumsAsset)
+	}
+
+	fmt.Printf("Downloading %s\n", asset.Name)
+	data, err := download(ctx, asset)
+	if err != nil {
+		return err
+	}
+	if err := verifyChecksum(checksums, asset.Name, data); err != nil {
+		return err
+	}
+	tracef("verified sha256 of %s", asset.Name)
+
+	binary, err := extractBinary(asset.Name, data)
+	if err != nil {
+		return err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+	if err := replaceExecutable(exe, binary); err != nil {
+		return fmt.Errorf("failed to replace %s: %v", exe, err)
+	}
+	fmt.Printf("Updated %s from %s to %s\n", exe, check.Current, check.Latest)
+	return nil
+}
+
+// confirmUpdate asks before the running binary is replaced. Anything but a yes
+// (including no answer at all) leaves it alone.
+func confirmUpdate(ctx context.Context, in io.Reader, out io.Writer, check updateCheck) (bool, error) {
+	p := newPrompter(ctx, in, out)
+	ok, _ := p.confirm(fmt.Sprintf("Replace wol %s with %s from github.com/%s?", check.Current, check.Latest, releaseRepo))
+	if p.err != nil {
+		return false, p.err
+	}
+	if !ok {
+		fmt.Fprintf(out, "Not updating (pass --yes to update without asking)\n")
+	}
+	return ok, nil
+}
+
+// Run the update command.
+func updateCmd(ctx context.Context, args []string, aliases *Aliases) error {
+	check, err := checkForUpdate(ctx)
+	if err != nil {
+		return err
+	}
+
+	if cliFlags.Check {
+		if cliFlags.JSON {
+			return json.NewEncoder(stdout).Encode(check)
+		}
+		if check.UpdateAvailable {
+			fmt.Printf("A newer version of wol is available: %s (you have %s)\n", check.Latest, check.Current)
+			fmt.Printf("    %s\n", check.URL)
+		} else {
+			fmt.Printf("wol %s is up to date\n", check.Current)
+		}
+		return nil
+	}
+
+	if !check.UpdateAvailable {
+		fmt.Printf("wol %s is up to date\n", check.Current)
+		return nil
+	}
+
+	if !cliFlags.Yes {
+		ok, err := confirmUpdate(ctx, os.Stdin, os.Stdout, check)
+		if err != nil || !ok {
+			return err
+		}
+	}
+	return selfUpdate(ctx, check)
+}
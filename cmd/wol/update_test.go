@@ -0,0 +1,123 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestPlatformAsset(t *testing.T) {
+	rel := release{TagName: "v2.1.0", Assets: []releaseAsset{
+		{Name: "checksums.txt"},
+		{Name: "go-wol_2.1.0_darwin_arm64.tar.gz"},
+		{Name: "go-wol_2.1.0_linux_amd64.tar.gz"},
+		{Name: "wol-windows-amd64.zip"},
+	}}
+
+	asset, err := platformAsset(rel, "linux", "amd64")
+	assert.Nil(t, err)
+	assert.Equal(t, "go-wol_2.1.0_linux_amd64.tar.gz", asset.Name)
+
+	asset, err = platformAsset(rel, "windows", "amd64")
+	assert.Nil(t, err)
+	assert.Equal(t, "wol-windows-amd64.zip", asset.Name)
+
+	_, err = platformAsset(rel, "linux", "arm")
+	assert.NotNil(t, err)
+
+	// An arm build must not pick up the arm64 one, or the other way around.
+	rel.Assets = append(rel.Assets,
+		releaseAsset{Name: "go-wol_2.1.0_linux_arm64.tar.gz"},
+		releaseAsset{Name: "go-wol_2.1.0_linux_arm.tar.gz"})
+	asset, err = platformAsset(rel, "linux", "arm")
+	assert.Nil(t, err)
+	assert.Equal(t, "go-wol_2.1.0_linux_arm.tar.gz", asset.Name)
+	asset, err = platformAsset(rel, "linux", "arm64")
+	assert.Nil(t, err)
+	assert.Equal(t, "go-wol_2.1.0_linux_arm64.tar.gz", asset.Name)
+
+	rel.Assets = []releaseAsset{{Name: "go-wol_2.1.0_linux_arm64.tar.gz"}}
+	_, err = platformAsset(rel, "linux", "arm")
+	assert.NotNil(t, err)
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	data := []byte("new wol binary")
+	sum := sha256.Sum256(data)
+	checksums := []byte("0000  other\n" + hex.EncodeToString(sum[:]) + "  wol_linux_amd64\n")
+
+	assert.Nil(t, verifyChecksum(checksums, "wol_linux_amd64", data))
+	assert.NotNil(t, verifyChecksum(checksums, "wol_linux_amd64", []byte("tampered")))
+	assert.NotNil(t, verifyChecksum(checksums, "wol_linux_arm64", data))
+}
+
+func TestExtractBinary(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, body := range map[string]string{"README.md": "readme", "go-wol/wol": "binary"} {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(body)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(body))
+	}
+	tw.Close()
+	gz.Close()
+
+	bs, err := extractBinary("wol_linux_amd64.tar.gz", buf.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, "binary", string(bs))
+
+	bs, err = extractBinary("wol_linux_amd64", []byte("raw"))
+	assert.Nil(t, err)
+	assert.Equal(t, "raw", string(bs))
+}
+
+func TestReplaceExecutable(t *testing.T) {
+	exe := filepath.Join(t.TempDir(), "wol")
+	assert.Nil(t, os.WriteFile(exe, []byte("old"), 0755))
+
+	assert.Nil(t, replaceExecutable(exe, []byte("new")))
+	bs, err := os.ReadFile(exe)
+	assert.Nil(t, err)
+	assert.Equal(t, "new", string(bs))
+
+	fi, err := os.Stat(exe)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0755), fi.Mode().Perm())
+
+	// No temporary files are left behind.
+	entries, _ := os.ReadDir(filepath.Dir(exe))
+	assert.Equal(t, 1, len(entries))
+}
+
+func TestConfirmUpdate(t *testing.T) {
+	check := updateCheck{Current: "2.0.2", Latest: "2.1.0"}
+	for _, tc := range []struct {
+		input    string
+		expected bool
+	}{
+		{"y\n", true},
+		{"yes\n", true},
+		{"n\n", false},
+		{"\n", false},
+		{"", false}, // no terminal to ask
+	} {
+		var out bytes.Buffer
+		ok, err := confirmUpdate(context.Background(), strings.NewReader(tc.input), &out, check)
+		assert.Nil(t, err, tc.input)
+		assert.Equal(t, tc.expected, ok, tc.input)
+		assert.Contains(t, out.String(), "Replace wol 2.0.2 with 2.1.0 from github.com/"+releaseRepo)
+	}
+}
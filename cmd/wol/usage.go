@@ -23,31 +23,82 @@ var (
 		{`alias`, `stores an alias to a mac address`},
 		{`remove`, `removes an alias or a mac address`},
 		{`interfaces`, `lists all available network interfaces`},
+		{`arp`, `prints or installs a static arp entry for unicast wake`},
+		{`reconcile`, `diffs aliases against an external inventory`},
+		{`db`, `manages the alias db (snapshot)`},
+		{`site`, `manages named network profiles (list, add, remove)`},
+		{`update`, `installs (or with --check reports) a newer release`},
+		{`check`, `checks (or with --fix enables) wake-on-lan on a host over ssh`},
+		{`init`, `interactively sets up a site and aliases`},
 	}
 
 	validOptions = []struct {
 		short, long, description string
 	}{
-		{`v`, `version`, `prints the application version`},
+		{`v`, `version`, `prints the application version and build details`},
 		{`h`, `help`, `prints this help menu`},
 		{`d`, `db-dir`, `directory to store alias db`},
 		{`a`, `db-name`, `bold db file name (default "bolt.db")`},
 		{`c`, `no-color`, `disables ANSI color`},
 		{`p`, `port`, `udp port to send bcast packet to`},
-		{`b`, `bcast`, `broadcast IP to send packet to`},
+		{`b`, `bcast`, `broadcast (or IPv6 multicast) IP to send packet to`},
 		{`i`, `interface`, `outbound interface to broadcast using`},
+		{``, `dns`, `dns server used to resolve hostnames`},
+		{`u`, `unicast`, `host IP to send packet to (instead of bcast)`},
+		{``, `ssh`, `host to run arp or check commands on (user@host)`},
+		{``, `source`, `reconcile source: csv or netbox (default "csv")`},
+		{``, `url`, `reconcile source file or url`},
+		{``, `token`, `api token for the reconcile source`},
+		{``, `add`, `adds aliases missing from the db when reconciling`},
+		{``, `ansible-inventory`, `lists aliases as an ansible inventory`},
+		{``, `cooldown`, `skips wakes repeated within this duration (eg. 30s)`},
+		{``, `read-only`, `opens the alias db without allowing changes`},
+		{``, `lock-timeout`, `time to wait for a locked alias db (default 5s)`},
+		{``, `recover`, `salvages a corrupt alias db into a fresh one`},
+		{``, `keep`, `number of db snapshots to keep (default 10)`},
+		{`t`, `timeout`, `time limit for all network operations (eg. 10s)`},
+		{``, `verbose`, `traces each step of sending the packet to stderr`},
+		{``, `json`, `reports errors as json objects on stderr`},
+		{``, `template`, `template for generated alias names (eg. lab-{{.N}})`},
+		{``, `mac-list`, `file with one mac (and optional interface) per line`},
+		{``, `tag`, `tag to add to generated aliases (repeatable)`},
+		{``, `start`, `first number used for generated aliases (default 1)`},
+		{``, `where`, `only list or wake aliases matching an expression`},
+		{``, `site`, `network profile to wake with (see site command)`},
+		{``, `icmp-wait`, `waits this long for icmp errors after sending (eg. 200ms)`},
+		{``, `check`, `only checks for a newer release when updating`},
+		{``, `password`, `SecureOn password to append to the packet (eg. 01:02:03:04:05:06)`},
+		{``, `fix`, `enables magic packet wakes when the check fails`},
+		{``, `raw`, `sends a raw ethernet frame on -i instead of udp (linux)`},
+		{``, `force`, `overwrites existing aliases when generating them`},
+		{`y`, `yes`, `replaces the running binary without asking when updating`},
 	}
 
 	usageString = `Usage:
 
     To wake up a machine:
-        <cyan>wol</cyan> [<options>] <yellow>wake</yellow> <mac address | alias> <optional interface>
+        <cyan>wol</cyan> [<options>] <yellow>wake</yellow> <mac address | alias | scheme:name> <optional interface>
+
+    Targets of the form scheme:name are resolved by running the
+    "wol-resolve-<scheme> <name>" command, which should print the MAC.
+
+    To pick an interface and add aliases interactively:
+        <cyan>wol</cyan> [<options>] <yellow>init</yellow>
 
     To store an alias:
         <cyan>wol</cyan> [<options>] <yellow>alias</yellow> <alias> <mac address> <optional interface>
 
-    To view aliases:
-        <cyan>wol</cyan> [<options>] <yellow>list</yellow>
+    To attach metadata to an alias (an empty value removes the key):
+        <cyan>wol</cyan> [<options>] <yellow>alias set</yellow> <alias> <key=value>...
+
+    To store numbered aliases for a list of mac addresses:
+        <cyan>wol</cyan> [<options>] <yellow>alias generate</yellow> --template <template> --mac-list <file> [--tag <tag>]
+
+    To view aliases (optionally as an ansible inventory):
+        <cyan>wol</cyan> [<options>] <yellow>list</yellow> [--ansible-inventory] [--where <expression>]
+
+    To wake every alias matching an expression:
+        <cyan>wol</cyan> [<options>] <yellow>wake</yellow> --where <expression>
 
     To delete aliases:
         <cyan>wol</cyan> [<options>] <yellow>remove</yellow> <alias>
@@ -55,6 +106,26 @@ var (
     To list network interfaces:
         <cyan>wol</cyan> [<options>] <yellow>interfaces</yellow>
 
+    To print (or install with --ssh) a static arp entry for unicast wake:
+        <cyan>wol</cyan> [<options>] <yellow>arp</yellow> <mac address | alias> <ip>
+
+    To store a named network profile, and wake using it (a site whose subnet
+    matches a connected network is used when --site is not given):
+        <cyan>wol</cyan> [<options>] <yellow>site add</yellow> <site> iface=<interface> bcast=<ip> port=<port> subnet=<cidr>
+        <cyan>wol</cyan> [<options>] <yellow>wake</yellow> --site <site> <mac address | alias>
+
+    To check for (or install) a newer release:
+        <cyan>wol</cyan> [<options>] <yellow>update</yellow> [--check | --yes]
+
+    To check (or --fix) that a linux host's NIC is armed for magic packets:
+        <cyan>wol</cyan> [<options>] <yellow>check</yellow> <mac address | alias> [--ssh <user@host>] [--fix]
+
+    To compare aliases against an inventory (csv or netbox):
+        <cyan>wol</cyan> [<options>] <yellow>reconcile</yellow> --source <csv | netbox> --url <url> [--token <token>] [--add]
+
+    To snapshot the alias db (keeping the last N snapshots):
+        <cyan>wol</cyan> [<options>] <yellow>db snapshot</yellow> [--keep N]
+
     The following MAC addresses are valid and will match:
     01-23-45-56-67-89, 89:AB:CD:EF:00:12, 89:ab:cd:ef:00:12
 
@@ -89,7 +160,11 @@ func getAllCommands() string {
 func getAllOptions() string {
 	options := ""
 	for _, o := range validOptions {
-		options += fmt.Sprintf("    <yellow>-%s --%-10s</yellow>    %s\n", o.short, o.long, o.description)
+		short := "  "
+		if o.short != "" {
+			short = "-" + o.short
+		}
+		options += fmt.Sprintf("    <yellow>%s --%-18s</yellow>    %s\n", short, o.long, o.description)
 	}
 	return options
 }
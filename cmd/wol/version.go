@@ -0,0 +1,180 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/sabhiram/go-wol/wol"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+var (
+	// releaseRepo is the GitHub repository (`owner/name`) which `update`
+	// installs releases from. Builds of a fork set their own with
+	// `-ldflags "-X main.releaseRepo=owner/name"`, or an empty one to disable
+	// updates.
+	releaseRepo = "playdelphi/go-wol"
+
+	// githubAPI is the base URL of the GitHub API.
+	githubAPI = "https://api.github.com"
+
+	// buildDate is set by release builds with
+	// `-ldflags "-X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"`.
+	buildDate string
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// versionInfo describes the running binary.
+type versionInfo struct {
+	Version    string `json:"version"`
+	Commit     string `json:"commit,omitempty"`
+	Modified   bool   `json:"modified,omitempty"`
+	CommitDate string `json:"commit_date,omitempty"`
+	BuildDate  string `json:"build_date,omitempty"`
+	GoVersion  string `json:"go_version"`
+	Platform   string `json:"platform"`
+}
+
+// getVersionInfo collects the version of the binary, along with the VCS details
+// the Go toolchain embeds when building from a checkout.
+func getVersionInfo() versionInfo {
+	info := versionInfo{
+		Version:   wol.Version,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Commit = setting.Value
+			case "vcs.time":
+				info.CommitDate = setting.Value
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	return info
+}
+
+// printVersion prints the version details. The first line is always just the
+// version, so scripts which read it keep working.
+func printVersion() error {
+	info := getVersionInfo()
+	if cliFlags.JSON {
+		return json.NewEncoder(stdout).Encode(info)
+	}
+
+	fmt.Printf("%s\n", info.Version)
+	if info.Commit != "" {
+		modified := ""
+		if info.Modified {
+			modified = " (modified)"
+		}
+		fmt.Printf("    commit: %s%s\n", info.Commit, modified)
+	}
+	if info.CommitDate != "" {
+		fmt.Printf("    date:   %s\n", info.CommitDate)
+	}
+	if info.BuildDate != "" {
+		fmt.Printf("    built:  %s\n", info.BuildDate)
+	}
+	fmt.Printf("    go:     %s %s\n", info.GoVersion, info.Platform)
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// release is the subset of a GitHub release which we care about.
+type release struct {
+	TagName string         `json:"tag_name"`
+	HTMLURL string         `json:"html_url"`
+	Assets  []releaseAsset `json:"assets"`
+}
+
+// releaseAsset is a file attached to a release.
+type releaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// latestRelease fetches the most recent release of releaseRepo from GitHub.
+func latestRelease(ctx context.Context) (release, error) {
+	var rel release
+	if releaseRepo == "" {
+		return rel, fmt.Errorf("this build of wol has no release repository to update from")
+	}
+	latestReleaseURL := githubAPI + "/repos/" + releaseRepo + "/releases/latest"
+	body, err := openURL(ctx, latestReleaseURL, "")
+	if err != nil {
+		return rel, err
+	}
+	defer body.Close()
+
+	if err := json.NewDecoder(body).Decode(&rel); err != nil {
+		return rel, fmt.Errorf("failed to parse release from %s: %v", latestReleaseURL, err)
+	}
+	if rel.TagName == "" {
+		return rel, fmt.Errorf("release from %s has no tag", latestReleaseURL)
+	}
+	return rel, nil
+}
+
+// compareVersions compares two `[v]major.minor.patch` versions, returning -1,
+// 0 or 1. Missing or non-numeric components count as 0.
+func compareVersions(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+// updateCheck is the result of `update --check`.
+type updateCheck struct {
+	Current         string `json:"current"`
+	Latest          string `json:"latest"`
+	UpdateAvailable bool   `json:"update_available"`
+	URL             string `json:"url,omitempty"`
+
+	release release
+}
+
+// checkForUpdate compares the running version against the latest release.
+func checkForUpdate(ctx context.Context) (updateCheck, error) {
+	rel, err := latestRelease(ctx)
+	if err != nil {
+		return updateCheck{}, err
+	}
+	return updateCheck{
+		Current:         wol.Version,
+		Latest:          strings.TrimPrefix(rel.TagName, "v"),
+		UpdateAvailable: compareVersions(wol.Version, rel.TagName) < 0,
+		URL:             rel.HTMLURL,
+		release:         rel,
+	}, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sabhiram/go-wol/wol"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestCompareVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b     string
+		expected int
+	}{
+		{"2.0.2", "v2.0.2", 0},
+		{"2.0.2", "v2.0.10", -1},
+		{"2.1.0", "2.0.9", 1},
+		{"2.0", "2.0.0", 0},
+		{"1.9.9", "v2", -1},
+	} {
+		assert.Equal(t, tc.expected, compareVersions(tc.a, tc.b), tc.a+" vs "+tc.b)
+	}
+}
+
+func TestCheckForUpdate(t *testing.T) {
+	tag := "v99.0.0"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tag_name": "` + tag + `", "html_url": "https://example.com/release"}`))
+	}))
+	defer server.Close()
+
+	saved := githubAPI
+	githubAPI = server.URL
+	defer func() { githubAPI = saved }()
+
+	check, err := checkForUpdate(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, wol.Version, check.Current)
+	assert.Equal(t, "99.0.0", check.Latest)
+	assert.True(t, check.UpdateAvailable)
+	assert.Equal(t, "https://example.com/release", check.URL)
+
+	tag = "v" + wol.Version
+	check, err = checkForUpdate(context.Background())
+	assert.Nil(t, err)
+	assert.False(t, check.UpdateAvailable)
+
+	// Builds without a release repository can not update.
+	savedRepo := releaseRepo
+	releaseRepo = ""
+	defer func() { releaseRepo = savedRepo }()
+	_, err = checkForUpdate(context.Background())
+	assert.NotNil(t, err)
+}
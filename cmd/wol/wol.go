@@ -3,13 +3,17 @@ package main
 ////////////////////////////////////////////////////////////////////////////////
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
 	"os"
+	"os/signal"
 	"os/user"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/mattn/go-colorable"
@@ -22,33 +26,86 @@ import (
 
 const (
 	defaultDBDir = "/.config/go-wol"
+
+	// exitCodeInterrupted is the conventional exit code after a SIGINT, and
+	// exitCodeTerminated the one after a SIGTERM.
+	exitCodeInterrupted = 130
+	exitCodeTerminated  = 143
 )
 
 var (
 	// Define holders for the cli arguments we wish to parse.
 	cliFlags struct {
-		Version            bool   `short:"v" long:"version"`
-		DBDir              string `short:"d" long:"db-dir" default:""`
-		DBName             string `short:"a" long:"db-name" default:"bolt.db"`
-		Help               bool   `short:"h" long:"help"`
-		NoColor            bool   `short:"n" long:"no-color"`
-		BroadcastInterface string `short:"i" long:"interface" default:""`
-		BroadcastIP        string `short:"b" long:"bcast" default:"255.255.255.255"`
-		UDPPort            string `short:"p" long:"port" default:"9"`
+		Version            bool          `short:"v" long:"version"`
+		DBDir              string        `short:"d" long:"db-dir" default:""`
+		DBName             string        `short:"a" long:"db-name" default:"bolt.db"`
+		Help               bool          `short:"h" long:"help"`
+		NoColor            bool          `short:"n" long:"no-color"`
+		BroadcastInterface string        `short:"i" long:"interface" default:""`
+		BroadcastIP        string        `short:"b" long:"bcast" default:"255.255.255.255"`
+		UDPPort            string        `short:"p" long:"port" default:"9"`
+		DNSServer          string        `long:"dns" default:""`
+		UnicastIP          string        `short:"u" long:"unicast" default:""`
+		SSHTarget          string        `long:"ssh" default:""`
+		ReconcileSource    string        `long:"source" default:"csv"`
+		ReconcileURL       string        `long:"url" default:""`
+		ReconcileToken     string        `long:"token" default:""`
+		ReconcileAdd       bool          `long:"add"`
+		AnsibleInventory   bool          `long:"ansible-inventory"`
+		Cooldown           time.Duration `long:"cooldown" default:"0s"`
+		ReadOnly           bool          `long:"read-only"`
+		LockTimeout        time.Duration `long:"lock-timeout" default:"5s"`
+		Recover            bool          `long:"recover"`
+		Keep               int           `long:"keep" default:"10"`
+		Timeout            time.Duration `short:"t" long:"timeout" default:"0s"`
+		Verbose            bool          `long:"verbose"`
+		JSON               bool          `long:"json"`
+		Template           string        `long:"template" default:""`
+		MACList            string        `long:"mac-list" default:""`
+		Tags               []string      `long:"tag"`
+		Start              int           `long:"start" default:"1"`
+		Where              string        `long:"where" default:""`
+		Site               string        `long:"site" default:""`
+		ICMPWait           time.Duration `long:"icmp-wait" default:"0s"`
+		Check              bool          `long:"check"`
+		Password           string        `long:"password" default:""`
+		Fix                bool          `long:"fix"`
+		Raw                bool          `long:"raw"`
+		Force              bool          `long:"force"`
+		Yes                bool          `short:"y" long:"yes"`
 	}
 	stdout = colorable.NewColorableStdout()
+
+	// cliParser is the parser for cliFlags, which knows which options were
+	// given on the command line.
+	cliParser = newCLIParser()
 )
 
 ////////////////////////////////////////////////////////////////////////////////
 
 // listNetworkInterfaces 返回所有可用的网络接口信息
 func listNetworkInterfaces() error {
-	interfaces, err := net.Interfaces()
+	summaries, err := interfaceSummaries()
 	if err != nil {
-		return fmt.Errorf("failed to get network interfaces: %v", err)
+		return err
 	}
 
 	fmt.Println("Available network interfaces:")
+	for _, summary := range summaries {
+		fmt.Printf("  %s\n", summary)
+	}
+	return nil
+}
+
+// interfaceSummaries describes each interface which is usable for sending, as
+// "name: ipv4 (MAC: mac)".
+func interfaceSummaries() ([]string, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get network interfaces: %v", err)
+	}
+
+	var summaries []string
 	for _, iface := range interfaces {
 		// 跳过回环接口和未启用的接口
 		if iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagUp == 0 {
@@ -70,10 +127,21 @@ func listNetworkInterfaces() error {
 		}
 
 		if ipv4Addr != "" {
-			fmt.Printf("  %s: %s (MAC: %s)\n", iface.Name, ipv4Addr, iface.HardwareAddr.String())
+			summaries = append(summaries, fmt.Sprintf("%s: %s (MAC: %s)", iface.Name, ipv4Addr, iface.HardwareAddr.String()))
 		}
 	}
-	return nil
+	return summaries, nil
+}
+
+// interfaceError returns an error about the interface `iface` which can not be
+// used for sending, suggesting the interfaces which can.
+func interfaceError(code, iface, message string) error {
+	ce := &cliError{Code: code, Param: "interface", Message: message}
+	summaries, _ := interfaceSummaries()
+	for _, summary := range summaries {
+		ce.Hints = append(ce.Hints, "available interface "+summary)
+	}
+	return ce
 }
 
 // ipFromInterface 从网络接口名称返回 `*net.UDPAddr`
@@ -82,14 +150,12 @@ func ipFromInterface(iface string) (*net.UDPAddr, error) {
 	ief, err := net.InterfaceByName(iface)
 	if err != nil {
 		// 如果接口不存在，列出可用接口供用户参考
-		fmt.Printf("Interface '%s' not found. ", iface)
-		listNetworkInterfaces()
-		return nil, fmt.Errorf("interface '%s' not found", iface)
+		return nil, interfaceError(codeInterfaceNotFound, iface, fmt.Sprintf("interface '%s' not found", iface))
 	}
 
 	// 检查接口是否启用
 	if ief.Flags&net.FlagUp == 0 {
-		return nil, fmt.Errorf("interface '%s' is not up", iface)
+		return nil, interfaceError(codeInterfaceUnusable, iface, fmt.Sprintf("interface '%s' is not up", iface))
 	}
 
 	addrs, err := ief.Addrs()
@@ -98,7 +164,7 @@ func ipFromInterface(iface string) (*net.UDPAddr, error) {
 	}
 
 	if len(addrs) <= 0 {
-		return nil, fmt.Errorf("no address associated with interface '%s'", iface)
+		return nil, interfaceError(codeInterfaceUnusable, iface, fmt.Sprintf("no address associated with interface '%s'", iface))
 	}
 
 	// 查找有效的IPv4地址
@@ -117,16 +183,27 @@ func ipFromInterface(iface string) (*net.UDPAddr, error) {
 	}
 
 	if len(validAddrs) == 0 {
-		return nil, fmt.Errorf("no valid IPv4 address found for interface '%s'", iface)
+		return nil, interfaceError(codeInterfaceUnusable, iface, fmt.Sprintf("no valid IPv4 address found for interface '%s'", iface))
 	}
 
-	return nil, fmt.Errorf("no suitable address found for interface '%s'", iface)
+	return nil, interfaceError(codeInterfaceUnusable, iface, fmt.Sprintf("no suitable address found for interface '%s'", iface))
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 
 // Run the alias command.
-func aliasCmd(args []string, aliases *Aliases) error {
+func aliasCmd(ctx context.Context, args []string, aliases *Aliases) error {
+	// A regular alias always has a MAC, so `alias generate` on its own is
+	// unambiguous.
+	if len(args) == 1 && args[0] == "generate" {
+		return aliasGenerateCmd(aliases)
+	}
+
+	// Likewise, an interface never contains `=`, so `alias set <alias> k=v`
+	// can not be mistaken for an alias named "set".
+	if len(args) >= 3 && args[0] == "set" && isAssignment(args[2:]) {
+		return aliasSetCmd(args[1], args[2:], aliases)
+	}
 	if len(args) >= 2 {
 		var eth string
 		if len(args) > 2 {
@@ -136,119 +213,270 @@ func aliasCmd(args []string, aliases *Aliases) error {
 		alias, mac := args[0], args[1]
 		return aliases.Add(alias, mac, eth)
 	}
-	return errors.New("alias command requires a <name> and a <mac>")
+	return usageError("", "alias command requires a <name> and a <mac>")
 }
 
 // Run the list command.
-func listCmd(args []string, aliases *Aliases) error {
+func listCmd(ctx context.Context, args []string, aliases *Aliases) error {
 	mp, err := aliases.List()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to get list of aliases: %v\n", err)
 		return err
 	}
-	if len(mp) == 0 {
+	names, err := selectAliases(cliFlags.Where, aliases, mp)
+	if err != nil {
+		return err
+	}
+	if cliFlags.Where != "" {
+		selected := make(map[string]MacIface, len(names))
+		for _, alias := range names {
+			selected[alias] = mp[alias]
+		}
+		mp = selected
+	}
+
+	if cliFlags.AnsibleInventory {
+		bs, err := ansibleInventory(mp)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(bs))
+		return nil
+	}
+	if len(mp) == 0 && cliFlags.Where != "" {
+		fmt.Printf("No aliases match \"%s\"\n", cliFlags.Where)
+	} else if len(mp) == 0 {
 		fmt.Printf("No aliases found! Add one with \"wol alias <name> <mac>\"\n")
 	} else {
-		for alias, mi := range mp {
-			fmt.Printf("    %s - %s %s\n", alias, mi.Mac, mi.Iface)
+		for _, alias := range names {
+			mi := mp[alias]
+			fmt.Printf("    %s - %s %s %s\n", alias, mi.Mac, mi.Iface, formatFields(mi))
 		}
 	}
 	return nil
 }
 
 // Run the remove command.
-func removeCmd(args []string, aliases *Aliases) error {
+func removeCmd(ctx context.Context, args []string, aliases *Aliases) error {
 	if len(args) > 0 {
 		alias := args[0]
 		return aliases.Del(alias)
 	}
-	return errors.New("remove command requires a <name> of an alias")
+	return usageError("", "remove command requires a <name> of an alias")
 }
 
 // Run the interfaces command - 列出所有可用的网络接口
-func interfacesCmd(args []string, aliases *Aliases) error {
+func interfacesCmd(ctx context.Context, args []string, aliases *Aliases) error {
 	return listNetworkInterfaces()
 }
 
 // Run the wake command.
-func wakeCmd(args []string, aliases *Aliases) error {
+func wakeCmd(ctx context.Context, args []string, aliases *Aliases) error {
+	if err := useSite(aliases); err != nil {
+		return err
+	}
+	if len(args) <= 0 && cliFlags.Where != "" {
+		return wakeWhereCmd(ctx, aliases)
+	}
 	if len(args) <= 0 {
-		return errors.New("No mac address specified to wake command")
+		return usageError("mac", "No mac address specified to wake command")
+	}
+	return wakeTarget(ctx, args[0], aliases)
+}
+
+// Wake every alias which matches the `--where` expression. A failure to wake
+// one alias does not stop the others from being woken.
+func wakeWhereCmd(ctx context.Context, aliases *Aliases) error {
+	mp, err := aliases.List()
+	if err != nil {
+		return err
+	}
+	names, err := selectAliases(cliFlags.Where, aliases, mp)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return usageError("where", fmt.Sprintf("No aliases match \"%s\"", cliFlags.Where))
+	}
+
+	var failed []string
+	for _, alias := range names {
+		if err := wakeTarget(ctx, alias, aliases); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Failed to wake %s: %v\n", alias, err)
+			failed = append(failed, alias)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to wake %d of %d aliases: %s", len(failed), len(names), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// Wake a single target, which may be an alias, a resolver target or a MAC.
+func wakeTarget(ctx context.Context, target string, aliases *Aliases) error {
+	// First we need to see if the target is actually an alias (or something
+	// an external resolver knows about), if it is: we set the eth interface
+	// based on the stored item, and set the macAddr based on the entry.
+	mi, err := lookupTarget(ctx, target, aliases)
+	if err != nil {
+		return err
 	}
 
 	// bcastInterface can be "eth0", "eth1", etc.. An empty string implies
 	// that we use the default interface when sending the UDP packet (nil).
-	bcastInterface := ""
-	macAddr := args[0]
+	macAddr, bcastInterface := mi.Mac, mi.Iface
 
-	// First we need to see if this macAddr is actually an alias, if it is:
-	// we set the eth interface based on the stored item, and set the macAddr
-	// based on the alias of the entry.
-	mi, err := aliases.Get(macAddr)
-	if err == nil {
-		macAddr = mi.Mac
-		bcastInterface = mi.Iface
+	// Coalesce repeated wakes for the same MAC within the cooldown window.
+	if cliFlags.Cooldown > 0 {
+		last, err := aliases.LastWake(macAddr)
+		if err != nil {
+			return err
+		}
+		if since := time.Since(last); since < cliFlags.Cooldown {
+			fmt.Printf("Already waking MAC %s (last packet sent %s ago), skipping\n",
+				macAddr, since.Round(time.Second))
+			return nil
+		}
+		tracef("last wake for %s was %s ago, outside the %s cooldown", macAddr, time.Since(last).Round(time.Second), cliFlags.Cooldown)
+		if aliases.readOnly {
+			fmt.Fprintf(os.Stderr, "Warning: the alias db is read-only, so this wake is not recorded for --cooldown\n")
+		}
 	}
 
-	// Always use the interface specified in the command line, if it exists.
+	// Aliases without an interface of their own use the site's, and the
+	// interface specified in the command line is always used, if it exists.
+	if bcastInterface == "" && siteIface != "" {
+		tracef("interface %q from the site", siteIface)
+		bcastInterface = siteIface
+	}
 	if cliFlags.BroadcastInterface != "" {
+		tracef("interface %q from --interface overrides %q", cliFlags.BroadcastInterface, bcastInterface)
 		bcastInterface = cliFlags.BroadcastInterface
 	}
 
+	// Raw ethernet frames are sent straight out of an interface, so there is
+	// no destination address to resolve.
+	if cliFlags.Raw {
+		return wakeRaw(ctx, macAddr, bcastInterface, aliases)
+	}
+
+	// The address to broadcast to is usually the default `255.255.255.255` but
+	// can be overloaded by specifying an override in the CLI arguments. When a
+	// unicast IP is specified, the packet is sent directly to the host instead
+	// (this requires a static ARP entry for the host on the gateway).
+	dstIP := cliFlags.BroadcastIP
+	if cliFlags.UnicastIP != "" {
+		tracef("unicast to %s instead of broadcasting to %s", cliFlags.UnicastIP, cliFlags.BroadcastIP)
+		dstIP = cliFlags.UnicastIP
+	}
+	bcastAddr := net.JoinHostPort(dstIP, cliFlags.UDPPort)
+	udpAddr, err := resolveUDPAddr(ctx, bcastAddr)
+	if err != nil {
+		return err
+	}
+	tracef("destination %s resolved to %s", bcastAddr, udpAddr)
+
 	// Populate the local address in the event that the broadcast interface has
-	// been set.
+	// been set. IPv6 destinations (such as the all-nodes multicast address
+	// ff02::1) select the interface with their zone instead.
 	var localAddr *net.UDPAddr
-	if bcastInterface != "" {
+	switch {
+	case bcastInterface != "" && udpAddr.IP.To4() == nil:
+		if _, err := net.InterfaceByName(bcastInterface); err != nil {
+			return interfaceError(codeInterfaceNotFound, bcastInterface, fmt.Sprintf("interface '%s' not found", bcastInterface))
+		}
+		if udpAddr.Zone == "" {
+			udpAddr.Zone = bcastInterface
+		}
+		tracef("sending on interface %s using zone %s", bcastInterface, udpAddr.Zone)
+	case bcastInterface != "":
 		localAddr, err = ipFromInterface(bcastInterface)
 		if err != nil {
 			return err
 		}
+		tracef("binding to %s on interface %s", localAddr.IP, bcastInterface)
+	default:
+		tracef("no interface specified, letting the OS pick the local address")
 	}
 
-	// The address to broadcast to is usually the default `255.255.255.255` but
-	// can be overloaded by specifying an override in the CLI arguments.
-	bcastAddr := fmt.Sprintf("%s:%s", cliFlags.BroadcastIP, cliFlags.UDPPort)
-	udpAddr, err := net.ResolveUDPAddr("udp", bcastAddr)
+	// Build the magic packet.
+	mp, err := buildPacket(macAddr)
 	if err != nil {
 		return err
 	}
 
-	// Build the magic packet.
-	mp, err := wol.New(macAddr)
-	if err != nil {
-		return err
+	// Send it as a single UDP datagram, the ICMP feedback (if any) is read
+	// back from the same socket once the packet is written.
+	t := &wol.UDPBroadcast{
+		Addr:      udpAddr.String(),
+		LocalAddr: localAddr,
+		Sent: func(conn net.Conn) error {
+			tracef("socket %s -> %s", conn.LocalAddr(), conn.RemoteAddr())
+			tracef("packet written (no retries)")
+			if cliFlags.ICMPWait > 0 {
+				return icmpFeedback(conn, cliFlags.ICMPWait)
+			}
+			return nil
+		},
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		tracef("socket deadline set to %s", deadline.Format(time.RFC3339Nano))
 	}
 
-	// Grab a stream of bytes to send.
-	bs, err := mp.Marshal()
-	if err != nil {
+	fmt.Printf("Attempting to send a magic packet to MAC %s\n", macAddr)
+	fmt.Printf("... Broadcasting to: %s\n", bcastAddr)
+	if err := t.Send(ctx, mp); err != nil {
 		return err
 	}
 
-	// Grab a UDP connection to send our packet of bytes.
-	conn, err := net.DialUDP("udp", localAddr, udpAddr)
+	fmt.Printf("Magic packet sent successfully to %s\n", macAddr)
+	return aliases.RecordWake(macAddr, time.Now())
+}
+
+// Build the magic packet for `macAddr`, with the `--password` if one was given.
+func buildPacket(macAddr string) (*wol.MagicPacket, error) {
+	mp, err := wol.New(macAddr, wol.WithPassword(cliFlags.Password))
+	if errors.Is(err, wol.ErrInvalidPassword) {
+		return nil, &cliError{Code: codeInvalidArgument, Param: "password", Message: err.Error(), err: err}
+	} else if err != nil {
+		return nil, invalidMACError(err)
+	}
+	if cliFlags.Password != "" {
+		tracef("appending SecureOn password")
+	}
+	return mp, nil
+}
+
+// Wake `macAddr` with a raw ethernet frame broadcast on `iface`.
+func wakeRaw(ctx context.Context, macAddr, iface string, aliases *Aliases) error {
+	if iface == "" {
+		return usageError("raw", "--raw requires an interface to send on (-i <interface>)")
+	}
+	if _, err := net.InterfaceByName(iface); err != nil {
+		return interfaceError(codeInterfaceNotFound, iface, fmt.Sprintf("interface '%s' not found", iface))
+	}
+
+	mp, err := buildPacket(macAddr)
 	if err != nil {
 		return err
 	}
-	defer conn.Close()
 
 	fmt.Printf("Attempting to send a magic packet to MAC %s\n", macAddr)
-	fmt.Printf("... Broadcasting to: %s\n", bcastAddr)
-	n, err := conn.Write(bs)
-	if err == nil && n != 102 {
-		err = fmt.Errorf("magic packet sent was %d bytes (expected 102 bytes sent)", n)
-	}
-	if err != nil {
+	fmt.Printf("... Broadcasting raw ethernet frame (EtherType 0x%04x) on: %s\n", wol.EtherType, iface)
+	if err := mp.SendRaw(ctx, iface); err != nil {
 		return err
 	}
 
 	fmt.Printf("Magic packet sent successfully to %s\n", macAddr)
-	return nil
+	return aliases.RecordWake(macAddr, time.Now())
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 
-type cmdFnType func([]string, *Aliases) error
+type cmdFnType func(context.Context, []string, *Aliases) error
 
 var cmdMap = map[string]cmdFnType{
 	"alias":      aliasCmd,
@@ -256,10 +484,40 @@ var cmdMap = map[string]cmdFnType{
 	"remove":     removeCmd,
 	"wake":       wakeCmd,
 	"interfaces": interfacesCmd,
+	"arp":        arpCmd,
+	"reconcile":  reconcileCmd,
+	"db":         dbCmd,
+	"site":       siteCmd,
+	"update":     updateCmd,
+	"check":      checkCmd,
+	"init":       initCmd,
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 
+// newCLIParser returns a parser which fills in cliFlags.
+func newCLIParser() *flags.Parser {
+	return flags.NewParser(&cliFlags, flags.Default & ^flags.HelpFlag & ^flags.PrintErrors)
+}
+
+// flagSet returns true if the option `long` was given on the command line
+// (as opposed to holding its default value).
+func flagSet(long string) bool {
+	opt := cliParser.FindOptionByLongName(long)
+	return opt != nil && opt.IsSet() && !opt.IsSetDefault()
+}
+
+// checkFlags rejects options which can not work together.
+func checkFlags() error {
+	if cliFlags.ReadOnly && cliFlags.Cooldown > 0 {
+		return usageError("cooldown", "--cooldown records each wake in the alias db, which --read-only does not allow")
+	}
+	if cliFlags.ReadOnly && cliFlags.Recover {
+		return usageError("recover", "--recover writes a fresh alias db, which --read-only does not allow")
+	}
+	return nil
+}
+
 // Helper function to dump the usage and print an error if specified,
 // it also returns the exit code requested to the function (saves me a line).
 func printUsageGetExitCode(s string, e int) int {
@@ -272,19 +530,61 @@ func printUsageGetExitCode(s string, e int) int {
 
 func fatalOnError(err error) {
 	if err != nil {
-		fmt.Printf("Fatal error: %s\n", err.Error())
+		printError(err)
 		os.Exit(1)
 	}
 }
 
+// signalError is the cause of a command cancelled by a signal.
+type signalError struct {
+	sig os.Signal
+}
+
+func (e *signalError) Error() string {
+	if e.sig == syscall.SIGTERM {
+		return "terminated"
+	}
+	return "interrupted"
+}
+
+func (e *signalError) Unwrap() error {
+	return context.Canceled
+}
+
+// exitCode returns the conventional exit code after the signal.
+func (e *signalError) exitCode() int {
+	if e.sig == syscall.SIGTERM {
+		return exitCodeTerminated
+	}
+	return exitCodeInterrupted
+}
+
+// notifyContext is like signal.NotifyContext, but the context is cancelled
+// with a *signalError naming the signal received.
+func notifyContext(sigs ...os.Signal) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	go func() {
+		select {
+		case sig := <-ch:
+			cancel(&signalError{sig})
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(ch)
+		cancel(nil)
+	}
+}
+
 // Main entry point for binary.
 func main() {
 	var args []string
 	var err error
 
 	// Parse arguments which might get passed to "wol".
-	parser := flags.NewParser(&cliFlags, flags.Default & ^flags.HelpFlag)
-	args, err = parser.Parse()
+	args, err = cliParser.Parse()
 
 	// Disable color if needed.
 	if cliFlags.NoColor {
@@ -294,9 +594,12 @@ func main() {
 	ec := 0
 	switch {
 
-	// Parse Error, print usage.
+	// Parse Error, print usage (unless a wrapper asked for json).
+	case err != nil && cliFlags.JSON:
+		fatalOnError(usageError("", err.Error()))
+
 	case err != nil:
-		fmt.Print(err.Error())
+		fmt.Println(err.Error())
 		ec = printUsageGetExitCode("", 1)
 
 	// No arguments, or help requested, print usage.
@@ -305,14 +608,19 @@ func main() {
 
 	// "--version" requested.
 	case cliFlags.Version:
-		fmt.Printf("%s\n", wol.Version)
+		fatalOnError(printVersion())
 
 	// Make sure we are being asked to run a something.
+	case len(args) == 0 && cliFlags.JSON:
+		fatalOnError(usageError("", "No command specified"))
+
 	case len(args) == 0:
 		ec = printUsageGetExitCode("No command specified, see usage:\n", 1)
 
 	// All other cases go here.
 	case true:
+		fatalOnError(checkFlags())
+
 		// Detect the current user to figure out what their ~ is.
 		usr, err := user.Current()
 		fatalOnError(err)
@@ -328,16 +636,51 @@ func main() {
 		// `bolt.db`.
 		dbPath := filepath.Join(dbDir, cliFlags.DBName)
 
-		// Load the list of aliases from the file at dbPath.
-		aliases, err := LoadAliases(dbPath)
+		// Load the list of aliases from the file at dbPath. If the db is
+		// damaged, salvage what we can when asked to.
+		aliasOpts := AliasOptions{
+			ReadOnly: cliFlags.ReadOnly,
+			Timeout:  cliFlags.LockTimeout,
+		}
+		aliases, err := LoadAliasesWithOptions(dbPath, aliasOpts)
+		var cerr *corruptDBError
+		if errors.As(err, &cerr) {
+			if !cliFlags.Recover {
+				fatalOnError(err)
+			}
+			fatalOnError(recoverAliasDB(dbPath, cliFlags.LockTimeout))
+			aliases, err = LoadAliasesWithOptions(dbPath, aliasOpts)
+		}
 		fatalOnError(err)
-		defer aliases.Close()
+
+		// Ctrl-C (or a SIGTERM) cancels the running command rather than
+		// killing it outright, so that it can wind down cleanly. This is only
+		// installed once the db is open, so interrupting a wait on the db
+		// lock still exits immediately.
+		ctx, stop := notifyContext(os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// All network operations of the command share a single deadline.
+		if cliFlags.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, cliFlags.Timeout)
+			defer cancel()
+		}
 
 		cmd, cmdArgs := strings.ToLower(args[0]), args[1:]
 		if fn, ok := cmdMap[cmd]; ok {
-			err = fn(cmdArgs, aliases)
+			err = fn(ctx, cmdArgs, aliases)
 		} else {
-			err = wakeCmd(args, aliases)
+			err = wakeCmd(ctx, args, aliases)
+		}
+		err = timeoutError(ctx, cliFlags.Timeout, err)
+
+		// Deferred calls do not run on os.Exit, so close the db explicitly.
+		aliases.Close()
+		var serr *signalError
+		if errors.As(context.Cause(ctx), &serr) {
+			printError(serr)
+			os.Exit(serr.exitCode())
 		}
 		fatalOnError(err)
 	}
@@ -3,7 +3,11 @@ package main
 ////////////////////////////////////////////////////////////////////////////////
 
 import (
+	"context"
+	"errors"
 	"net"
+	"os"
+	"syscall"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -42,3 +46,50 @@ func TestIPFromInterfaceNegative(t *testing.T) {
 		assert.NotNil(t, err)
 	}
 }
+
+func TestSignalError(t *testing.T) {
+	for _, tc := range []struct {
+		sig     os.Signal
+		code    int
+		message string
+	}{
+		{os.Interrupt, exitCodeInterrupted, "interrupted"},
+		{syscall.SIGTERM, exitCodeTerminated, "terminated"},
+	} {
+		err := &signalError{tc.sig}
+		assert.Equal(t, tc.code, err.exitCode())
+		assert.True(t, errors.Is(err, context.Canceled))
+
+		ce := toCLIError(err)
+		assert.Equal(t, codeInterrupted, ce.Code)
+		assert.Equal(t, tc.message, ce.Message)
+	}
+}
+
+func TestCheckFlags(t *testing.T) {
+	saved, savedParser := cliFlags, cliParser
+	defer func() { cliFlags, cliParser = saved, savedParser }()
+
+	for _, tc := range []struct {
+		args  []string
+		param string
+	}{
+		{[]string{"--cooldown", "30s"}, ""},
+		{[]string{"--read-only"}, ""},
+		{[]string{"--read-only", "--cooldown", "30s"}, "cooldown"},
+		{[]string{"--recover"}, ""},
+		{[]string{"--read-only", "--recover"}, "recover"},
+	} {
+		// Options which were not given keep their old value, so start over.
+		cliFlags, cliParser = saved, newCLIParser()
+		_, err := cliParser.ParseArgs(tc.args)
+		assert.Nil(t, err)
+
+		err = checkFlags()
+		if tc.param == "" {
+			assert.Nil(t, err, tc.args)
+		} else if assert.NotNil(t, err, tc.args) {
+			assert.Equal(t, tc.param, toCLIError(err).Param)
+		}
+	}
+}
@@ -5,6 +5,7 @@ package wol
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"net"
 	"regexp"
@@ -12,7 +13,16 @@ import (
 
 ////////////////////////////////////////////////////////////////////////////////
 
+const (
+	// packetSize is the size of a magic packet without a SecureOn password.
+	packetSize = 102
+)
+
 var (
+	// ErrInvalidPassword is returned (wrapped) for a malformed SecureOn
+	// password.
+	ErrInvalidPassword = errors.New("invalid SecureOn password")
+
 	delims = ":-"
 	reMAC  = regexp.MustCompile(`^([0-9a-fA-F]{2}[` + delims + `]){5}([0-9a-fA-F]{2})$`)
 )
@@ -23,31 +33,47 @@ var (
 type MACAddress [6]byte
 
 // MagicPacket is constituted of 6 bytes of 0xFF followed by 16-groups of the
-// destination MAC address.
+// destination MAC address, optionally followed by a 6 byte SecureOn password.
 type MagicPacket struct {
-	header  [6]byte
-	payload [16]MACAddress
+	header   [6]byte
+	payload  [16]MACAddress
+	password []byte
+
+	// port and iface are the defaults used when sending the packet.
+	port  int
+	iface string
 }
 
-// New returns a magic packet based on a mac address string.
-func New(mac string) (*MagicPacket, error) {
-	var packet MagicPacket
-	var macAddr MACAddress
+// parseMAC48 parses a 6 byte address written in the same format as a MAC.
+func parseMAC48(s string) (MACAddress, error) {
+	var addr MACAddress
 
-	hwAddr, err := net.ParseMAC(mac)
+	hwAddr, err := net.ParseMAC(s)
 	if err != nil {
-		return nil, err
+		return addr, err
 	}
 
 	// We only support 6 byte MAC addresses since it is much harder to use the
 	// binary.Write(...) interface when the size of the MagicPacket is dynamic.
-	if !reMAC.MatchString(mac) {
-		return nil, fmt.Errorf("%s is not a IEEE 802 MAC-48 address", mac)
+	if !reMAC.MatchString(s) {
+		return addr, fmt.Errorf("%s is not a IEEE 802 MAC-48 address", s)
 	}
 
 	// Copy bytes from the returned HardwareAddr -> a fixed size MACAddress.
-	for idx := range macAddr {
-		macAddr[idx] = hwAddr[idx]
+	copy(addr[:], hwAddr)
+	return addr, nil
+}
+
+// New returns a magic packet based on a mac address string. Options may add a
+// SecureOn password (WithPassword), and set the defaults used when the packet
+// is sent (WithPort, WithInterface).
+func New(mac string, opts ...Option) (*MagicPacket, error) {
+	var packet MagicPacket
+	o := newOptions(opts)
+
+	macAddr, err := parseMAC48(mac)
+	if err != nil {
+		return nil, err
 	}
 
 	// Setup the header which is 6 repetitions of 0xFF.
@@ -60,15 +86,92 @@ func New(mac string) (*MagicPacket, error) {
 		packet.payload[idx] = macAddr
 	}
 
+	if o.password != "" {
+		if packet.password, err = parsePassword(o.password); err != nil {
+			return nil, err
+		}
+	}
+	packet.port, packet.iface = o.port, o.iface
+
+	return &packet, nil
+}
+
+// NewWithPassword returns a magic packet for a NIC which requires a SecureOn
+// password. The password is 6 bytes written like a MAC address (for example
+// "01:02:03:04:05:06"), and is appended to the usual 102 bytes. Unlike
+// New(mac, WithPassword(password)), an empty password is an error.
+func NewWithPassword(mac, password string) (*MagicPacket, error) {
+	packet, err := New(mac)
+	if err != nil {
+		return nil, err
+	}
+
+	if packet.password, err = parsePassword(password); err != nil {
+		return nil, err
+	}
+	return packet, nil
+}
+
+// parsePassword parses a 6 byte SecureOn password.
+func parsePassword(password string) ([]byte, error) {
+	pw, err := parseMAC48(password)
+	if err != nil {
+		return nil, fmt.Errorf("%w (expected 6 bytes, eg. 01:02:03:04:05:06): %v", ErrInvalidPassword, err)
+	}
+	return pw[:], nil
+}
+
+// Unmarshal parses a magic packet, as received off the wire. The header must be
+// 6 bytes of 0xFF, followed by 16 identical copies of the MAC address, and
+// optionally a 6 byte SecureOn password (102 or 108 bytes in total).
+func Unmarshal(data []byte) (*MagicPacket, error) {
+	var packet MagicPacket
+	if len(data) != packetSize && len(data) != packetSize+len(MACAddress{}) {
+		return nil, fmt.Errorf("magic packet is %d bytes (expected %d or %d bytes)", len(data), packetSize, packetSize+len(MACAddress{}))
+	}
+
+	for idx := range packet.header {
+		if data[idx] != 0xFF {
+			return nil, fmt.Errorf("magic packet header byte %d is 0x%02x (expected 0xff)", idx, data[idx])
+		}
+		packet.header[idx] = 0xFF
+	}
+
+	payload := data[len(packet.header):]
+	for idx := range packet.payload {
+		copy(packet.payload[idx][:], payload[idx*len(MACAddress{}):])
+		if packet.payload[idx] != packet.payload[0] {
+			return nil, fmt.Errorf("magic packet mac address repetition %d does not match the first", idx)
+		}
+	}
+
+	if len(data) > packetSize {
+		packet.password = append([]byte(nil), data[packetSize:]...)
+	}
 	return &packet, nil
 }
 
-// Marshal serializes the magic packet structure into a 102 byte slice.
+// MAC returns the MAC address the packet wakes.
+func (mp *MagicPacket) MAC() net.HardwareAddr {
+	return net.HardwareAddr(mp.payload[0][:])
+}
+
+// Password returns the SecureOn password of the packet, or nil if it has none.
+func (mp *MagicPacket) Password() []byte {
+	return mp.password
+}
+
+// Marshal serializes the magic packet structure into a 102 byte slice (108
+// bytes with a SecureOn password).
 func (mp *MagicPacket) Marshal() ([]byte, error) {
 	var buf bytes.Buffer
-	if err := binary.Write(&buf, binary.BigEndian, mp); err != nil {
+	if err := binary.Write(&buf, binary.BigEndian, mp.header); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(&buf, binary.BigEndian, mp.payload); err != nil {
 		return nil, err
 	}
+	buf.Write(mp.password)
 
 	return buf.Bytes(), nil
 }
@@ -67,3 +67,121 @@ func TestMagicPacketMarshal(t *testing.T) {
 		assert.Equal(t, len(bs), tc.count)
 	}
 }
+
+func TestMagicPacketUnmarshal(t *testing.T) {
+	pkt, err := New("00:ff:01:03:00:00")
+	assert.Nil(t, err)
+	bs, err := pkt.Marshal()
+	assert.Nil(t, err)
+
+	parsed, err := Unmarshal(bs)
+	assert.Nil(t, err)
+	assert.Equal(t, pkt, parsed)
+	assert.Equal(t, "00:ff:01:03:00:00", parsed.MAC().String())
+	assert.Nil(t, parsed.Password())
+
+	pkt, err = NewWithPassword("00:ff:01:03:00:00", "01:02:03:04:05:06")
+	assert.Nil(t, err)
+	bs, err = pkt.Marshal()
+	assert.Nil(t, err)
+
+	parsed, err = Unmarshal(bs)
+	assert.Nil(t, err)
+	assert.Equal(t, pkt, parsed)
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6}, parsed.Password())
+}
+
+func TestMagicPacketUnmarshalNegative(t *testing.T) {
+	pkt, _ := New("00:ff:01:03:00:00")
+	valid, _ := pkt.Marshal()
+
+	badHeader := append([]byte(nil), valid...)
+	badHeader[3] = 0xFE
+	badRepetition := append([]byte(nil), valid...)
+	badRepetition[101] = 0x01
+
+	for _, bs := range [][]byte{
+		nil,
+		valid[:101],
+		append(valid, 0x00),
+		append(valid, 1, 2, 3, 4, 5, 6, 7),
+		badHeader,
+		badRepetition,
+	} {
+		_, err := Unmarshal(bs)
+		assert.NotNil(t, err)
+	}
+}
+
+func FuzzUnmarshal(f *testing.F) {
+	pkt, _ := New("00:11:22:33:44:55")
+	valid, _ := pkt.Marshal()
+	f.Add(valid)
+	f.Add(append(valid, 1, 2, 3, 4, 5, 6))
+	f.Add(valid[:50])
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		parsed, err := Unmarshal(data)
+		if err != nil {
+			return
+		}
+
+		// Anything which parses must serialize back to the same bytes.
+		bs, err := parsed.Marshal()
+		assert.Nil(t, err)
+		assert.Equal(t, data, bs)
+	})
+}
+
+func TestNewMagicPacketWithPassword(t *testing.T) {
+	pkt, err := NewWithPassword("00:ff:01:03:00:00", "01-02-03-04-05-06")
+	assert.Nil(t, err)
+
+	bs, err := pkt.Marshal()
+	assert.Nil(t, err)
+	assert.Equal(t, 108, len(bs))
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6}, bs[102:])
+	assert.Equal(t, []byte{0, 255, 1, 3, 0, 0}, bs[96:102])
+
+	for _, tc := range []struct {
+		mac, password string
+	}{
+		{"00:ff:01:03:00:00", ""},
+		{"00:ff:01:03:00:00", "01:02:03:04"},
+		{"00:ff:01:03:00:00", "01:02:03:04:05:06:07:08"},
+		{"00:ff:01:03:00:00", "secret"},
+		{"00x00:00:00:00:00", "01:02:03:04:05:06"},
+	} {
+		_, err := NewWithPassword(tc.mac, tc.password)
+		assert.NotNil(t, err, tc.password)
+	}
+
+	// A bad password can be told apart from a bad MAC.
+	_, err = New("00:ff:01:03:00:00", WithPassword("secret"))
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+	_, err = New("00x00:00:00:00:00", WithPassword("01:02:03:04:05:06"))
+	assert.NotNil(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidPassword)
+}
+
+func TestNewMagicPacketOptions(t *testing.T) {
+	pkt, err := New("00:ff:01:03:00:00", WithPassword("01:02:03:04:05:06"), WithPort(7), WithInterface("eth1"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6}, pkt.Password())
+	assert.Equal(t, 7, pkt.port)
+	assert.Equal(t, "eth1", pkt.iface)
+
+	// The options are the same as NewWithPassword, and do not change the
+	// bytes on the wire.
+	withPassword, err := NewWithPassword("00:ff:01:03:00:00", "01:02:03:04:05:06")
+	assert.Nil(t, err)
+	bs, err := pkt.Marshal()
+	assert.Nil(t, err)
+	expected, err := withPassword.Marshal()
+	assert.Nil(t, err)
+	assert.Equal(t, expected, bs)
+
+	_, err = New("00:ff:01:03:00:00", WithPassword("secret"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,62 @@
+package wol
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+const (
+	// EtherType is the Ethernet frame type used for magic packets sent
+	// directly at layer 2 (rather than inside a UDP datagram).
+	EtherType = 0x0842
+)
+
+var (
+	// ErrRawUnsupported is returned by SendRaw on platforms without raw
+	// socket support.
+	ErrRawUnsupported = errors.New("raw ethernet frames are not supported on this platform")
+
+	broadcastMAC = net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// ethernetFrame wraps `payload` in an Ethernet II header from `src` to `dst`
+// with the magic packet EtherType.
+func ethernetFrame(dst, src net.HardwareAddr, payload []byte) []byte {
+	frame := make([]byte, 0, 14+len(payload))
+	frame = append(frame, dst...)
+	frame = append(frame, src...)
+	frame = append(frame, byte(EtherType>>8), byte(EtherType&0xff))
+	return append(frame, payload...)
+}
+
+// SendRaw broadcasts the packet as a raw Ethernet frame (EtherType 0x0842) on
+// the interface named `iface`. Some firmwares only honor magic packets sent
+// this way. This needs raw socket privileges (root or CAP_NET_RAW), and is
+// currently only supported on Linux.
+func (mp *MagicPacket) SendRaw(ctx context.Context, iface string) error {
+	bs, err := mp.Marshal()
+	if err != nil {
+		return err
+	}
+
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return err
+	}
+	if len(ifi.HardwareAddr) != 6 {
+		return fmt.Errorf("interface %s is not an ethernet interface", iface)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return sendFrame(ifi, ethernetFrame(broadcastMAC, ifi.HardwareAddr, bs))
+}
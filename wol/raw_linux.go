@@ -0,0 +1,44 @@
+//go:build linux
+
+package wol
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// htons converts a short to network byte order, which is a no-op on big endian
+// hosts.
+func htons(v uint16) uint16 {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	return binary.NativeEndian.Uint16(b[:])
+}
+
+// sendFrame writes a complete Ethernet frame to `ifi` using an AF_PACKET
+// socket.
+func sendFrame(ifi *net.Interface, frame []byte) error {
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(htons(EtherType)))
+	if errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EACCES) {
+		return fmt.Errorf("raw ethernet frames need root or CAP_NET_RAW: %v", err)
+	}
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	addr := &syscall.SockaddrLinklayer{
+		Protocol: htons(EtherType),
+		Ifindex:  ifi.Index,
+		Halen:    6,
+	}
+	copy(addr.Addr[:], frame[:6])
+	return syscall.Sendto(fd, frame, 0, addr)
+}
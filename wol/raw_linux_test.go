@@ -0,0 +1,22 @@
+//go:build linux
+
+package wol
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Whatever the host's byte order, htons lays the value out big endian in
+// memory.
+func TestHtons(t *testing.T) {
+	var b [2]byte
+	binary.NativeEndian.PutUint16(b[:], htons(EtherType))
+	assert.Equal(t, [2]byte{0x08, 0x42}, b)
+}
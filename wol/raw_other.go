@@ -0,0 +1,16 @@
+//go:build !linux
+
+package wol
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"net"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// sendFrame is not implemented on this platform.
+func sendFrame(ifi *net.Interface, frame []byte) error {
+	return ErrRawUnsupported
+}
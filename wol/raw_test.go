@@ -0,0 +1,48 @@
+package wol
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestEthernetFrame(t *testing.T) {
+	pkt, err := New("00:11:22:33:44:55")
+	assert.Nil(t, err)
+	bs, err := pkt.Marshal()
+	assert.Nil(t, err)
+
+	src := net.HardwareAddr{2, 0, 0, 0, 0, 1}
+	frame := ethernetFrame(broadcastMAC, src, bs)
+	assert.Equal(t, 14+102, len(frame))
+	assert.Equal(t, []byte(broadcastMAC), frame[:6])
+	assert.Equal(t, []byte(src), frame[6:12])
+	assert.Equal(t, []byte{0x08, 0x42}, frame[12:14])
+
+	parsed, err := Unmarshal(frame[14:])
+	assert.Nil(t, err)
+	assert.Equal(t, pkt, parsed)
+}
+
+func TestSendRawNegative(t *testing.T) {
+	pkt, err := New("00:11:22:33:44:55")
+	assert.Nil(t, err)
+
+	assert.NotNil(t, pkt.SendRaw(context.Background(), "no-such-interface0"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ifaces, _ := net.Interfaces()
+	for _, ifi := range ifaces {
+		if len(ifi.HardwareAddr) == 6 {
+			assert.ErrorIs(t, pkt.SendRaw(ctx, ifi.Name), context.Canceled)
+			break
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package wol
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Transport delivers magic packets. UDPBroadcast, UDPUnicast and RawEthernet
+// are provided, and other transports (or mocks) can be used with
+// WithTransport.
+type Transport interface {
+	Send(ctx context.Context, mp *MagicPacket) error
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// UDPUnicast sends magic packets as UDP datagrams directly to the IP of the
+// sleeping host. Since the host can not answer ARP requests while it sleeps,
+// this needs a static ARP entry for it on the last router.
+type UDPUnicast struct {
+	// Addr is the "host:port" (or just host, see DefaultPort) to send to.
+	Addr string
+
+	// Port, if set, is the port to send to when Addr has none. It defaults
+	// to the port given to New (see WithPort), then DefaultPort.
+	Port int
+
+	// Interface, if set, is the interface to send on when LocalAddr is not
+	// set. It defaults to the interface given to New (see WithInterface).
+	Interface string
+
+	// LocalAddr, if set, is the address (and so interface) to send from.
+	LocalAddr *net.UDPAddr
+
+	// Resolver, if set, is used to resolve a hostname in Addr.
+	Resolver *net.Resolver
+
+	// Sent, if set, is called with the (connected) socket once the packet
+	// has been written, and its error is returned by Send. It can be used
+	// to log the addresses used, or to read back an ICMP error.
+	Sent func(conn net.Conn) error
+}
+
+// Send implements Transport.
+func (t *UDPUnicast) Send(ctx context.Context, mp *MagicPacket) error {
+	if t.Addr == "" {
+		return fmt.Errorf("unicast transport needs an address")
+	}
+	return sendUDP(ctx, mp, t)
+}
+
+// UDPBroadcast sends magic packets as UDP datagrams to a broadcast address. It
+// has the same fields as UDPUnicast, except that Addr defaults to
+// 255.255.255.255.
+type UDPBroadcast UDPUnicast
+
+// Send implements Transport.
+func (t *UDPBroadcast) Send(ctx context.Context, mp *MagicPacket) error {
+	u := UDPUnicast(*t)
+	if u.Addr == "" {
+		u.Addr = net.IPv4bcast.String()
+	}
+	return sendUDP(ctx, mp, &u)
+}
+
+// RawEthernet broadcasts magic packets as raw Ethernet frames (see SendRaw).
+type RawEthernet struct {
+	// Interface is the name of the interface to send on. It defaults to the
+	// interface given to New (see WithInterface).
+	Interface string
+}
+
+// Send implements Transport.
+func (t *RawEthernet) Send(ctx context.Context, mp *MagicPacket) error {
+	iface := t.Interface
+	if iface == "" {
+		iface = mp.iface
+	}
+	return mp.SendRaw(ctx, iface)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// sendUDP writes the packet as a single UDP datagram to `t.Addr`.
+func sendUDP(ctx context.Context, mp *MagicPacket, t *UDPUnicast) error {
+	bs, err := mp.Marshal()
+	if err != nil {
+		return err
+	}
+
+	port, iface := t.Port, t.Interface
+	if port == 0 {
+		port = mp.port
+	}
+	if iface == "" {
+		iface = mp.iface
+	}
+	addr, localAddr := withPort(t.Addr, port), t.LocalAddr
+	if iface != "" && localAddr == nil {
+		// IPv6 destinations are scoped to the interface with a zone, IPv4
+		// ones are reached by sending from the interface's address.
+		host, port, _ := net.SplitHostPort(addr)
+		ip, perr := netip.ParseAddr(host)
+		switch {
+		case perr == nil && ip.Is6() && !ip.Is4In6():
+			if ip.Zone() == "" {
+				addr = net.JoinHostPort(ip.WithZone(iface).String(), port)
+			}
+		default:
+			if localAddr, err = interfaceAddr(iface); err != nil {
+				return err
+			}
+		}
+	}
+
+	// The local address must only be set when we have one, a nil *UDPAddr
+	// is not a nil Addr.
+	dialer := net.Dialer{Resolver: t.Resolver}
+	if localAddr != nil {
+		dialer.LocalAddr = localAddr
+	}
+	conn, err := dialer.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	n, err := conn.Write(bs)
+	if err == nil && n != len(bs) {
+		err = fmt.Errorf("magic packet sent was %d bytes (expected %d bytes sent)", n, len(bs))
+	}
+	if err == nil && t.Sent != nil {
+		err = t.Sent(conn)
+	}
+	return err
+}
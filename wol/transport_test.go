@@ -0,0 +1,67 @@
+package wol
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// recorder is a Transport which keeps the packets it is asked to send.
+type recorder struct {
+	sent []*MagicPacket
+}
+
+func (r *recorder) Send(ctx context.Context, mp *MagicPacket) error {
+	r.sent = append(r.sent, mp)
+	return nil
+}
+
+func TestWithTransport(t *testing.T) {
+	r := &recorder{}
+	err := Wake("00:11:22:33:44:55", "ignored", WithTransport(r), WithPassword("01:02:03:04:05:06"))
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(r.sent)) {
+		assert.Equal(t, "00:11:22:33:44:55", r.sent[0].MAC().String())
+		assert.Equal(t, []byte{1, 2, 3, 4, 5, 6}, r.sent[0].Password())
+	}
+}
+
+func TestUDPTransports(t *testing.T) {
+	conn := listen(t)
+	defer conn.Close()
+
+	mp, err := New("00:11:22:33:44:55")
+	assert.Nil(t, err)
+
+	for _, transport := range []Transport{
+		&UDPBroadcast{Addr: conn.LocalAddr().String()},
+		&UDPUnicast{Addr: conn.LocalAddr().String()},
+	} {
+		assert.Nil(t, transport.Send(context.Background(), mp))
+		assert.Equal(t, mp, receive(t, conn))
+	}
+
+	// Sent sees the socket the packet went out on, and its error is Send's.
+	var remote string
+	boom := errors.New("boom")
+	err = (&UDPBroadcast{
+		Addr: conn.LocalAddr().String(),
+		Sent: func(c net.Conn) error {
+			remote = c.RemoteAddr().String()
+			return boom
+		},
+	}).Send(context.Background(), mp)
+	assert.Equal(t, boom, err)
+	assert.Equal(t, conn.LocalAddr().String(), remote)
+	assert.Equal(t, mp, receive(t, conn))
+
+	assert.NotNil(t, (&UDPUnicast{}).Send(context.Background(), mp))
+	assert.NotNil(t, (&RawEthernet{Interface: "no-such-interface0"}).Send(context.Background(), mp))
+}
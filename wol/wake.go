@@ -0,0 +1,162 @@
+package wol
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+const (
+	// DefaultPort is the UDP port magic packets are sent to when the
+	// destination does not specify one.
+	DefaultPort = 9
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Option configures how a magic packet is built and sent.
+type Option func(*options)
+
+type options struct {
+	password  string
+	port      int
+	iface     string
+	localAddr *net.UDPAddr
+	resolver  *net.Resolver
+	transport Transport
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithPassword appends a SecureOn password (see NewWithPassword) to the
+// packet.
+func WithPassword(password string) Option {
+	return func(o *options) {
+		o.password = password
+	}
+}
+
+// WithPort sets the UDP port the packet is sent to when the destination does
+// not specify one (instead of DefaultPort).
+func WithPort(port int) Option {
+	return func(o *options) {
+		o.port = port
+	}
+}
+
+// WithInterface sends the packet on the named interface. UDP packets are sent
+// from the interface's IPv4 address, or with the interface as the zone of an
+// IPv6 destination. Raw Ethernet frames are sent on it.
+func WithInterface(name string) Option {
+	return func(o *options) {
+		o.iface = name
+	}
+}
+
+// WithLocalAddr sends the packet from `addr`, which selects the outbound
+// interface.
+func WithLocalAddr(addr *net.UDPAddr) Option {
+	return func(o *options) {
+		o.localAddr = addr
+	}
+}
+
+// WithResolver resolves a destination hostname using `resolver` instead of
+// the system default (for example to query a specific DNS server).
+func WithResolver(resolver *net.Resolver) Option {
+	return func(o *options) {
+		o.resolver = resolver
+	}
+}
+
+// WithTransport sends the packet using `t` rather than as a UDP datagram.
+func WithTransport(t Transport) Option {
+	return func(o *options) {
+		o.transport = t
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Wake sends a magic packet for `mac` to `bcastAddr`. The destination is a
+// "host:port" pair, or just a host in which case DefaultPort (or WithPort) is
+// used.
+func Wake(mac, bcastAddr string, opts ...Option) error {
+	return WakeContext(context.Background(), mac, bcastAddr, opts...)
+}
+
+// WakeContext is like Wake, but gives up resolving the destination and
+// sending the packet once `ctx` is done.
+func WakeContext(ctx context.Context, mac, bcastAddr string, opts ...Option) error {
+	mp, err := New(mac, opts...)
+	if err != nil {
+		return err
+	}
+	return mp.SendContext(ctx, bcastAddr, opts...)
+}
+
+// Send sends the packet to `bcastAddr` (see Wake).
+func (mp *MagicPacket) Send(bcastAddr string, opts ...Option) error {
+	return mp.SendContext(context.Background(), bcastAddr, opts...)
+}
+
+// SendContext sends the packet to `bcastAddr`, giving up once `ctx` is done.
+// The packet is sent as is, so WithPassword has no effect here, while WithPort
+// and WithInterface override the ones given to New. If a transport was given
+// with WithTransport, it is used instead and `bcastAddr` is ignored.
+func (mp *MagicPacket) SendContext(ctx context.Context, bcastAddr string, opts ...Option) error {
+	o := newOptions(opts)
+
+	t := o.transport
+	if t == nil {
+		t = &UDPBroadcast{
+			Addr:      bcastAddr,
+			Port:      o.port,
+			Interface: o.iface,
+			LocalAddr: o.localAddr,
+			Resolver:  o.resolver,
+		}
+	}
+	return t.Send(ctx, mp)
+}
+
+// withPort appends `port` (or DefaultPort, if it is 0) to `addr` unless it
+// already has a port.
+func withPort(addr string, port int) string {
+	if _, _, err := net.SplitHostPort(addr); err == nil {
+		return addr
+	}
+	if port == 0 {
+		port = DefaultPort
+	}
+	return net.JoinHostPort(addr, strconv.Itoa(port))
+}
+
+// interfaceAddr returns the first IPv4 address of the interface `name`.
+func interfaceAddr(name string) (*net.UDPAddr, error) {
+	ifi, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+			return &net.UDPAddr{IP: ipNet.IP}, nil
+		}
+	}
+	return nil, fmt.Errorf("no IPv4 address found for interface %s", name)
+}
@@ -0,0 +1,176 @@
+package wol
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Target is a machine to wake with WakeAll.
+type Target struct {
+	// MAC is the MAC address of the machine.
+	MAC string
+
+	// Addr is the "host:port" (or just host, see DefaultPort) to send the
+	// packet to. It defaults to 255.255.255.255. A transport given with
+	// WithTransport has its own destination, so Addr must be empty then.
+	Addr string
+}
+
+// Result is the outcome of waking a single Target.
+type Result struct {
+	Target Target
+	Err    error
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// WakeAll sends magic packets to all `targets` concurrently, and returns the
+// result for each target (in the same order). Packets are sent from a single
+// UDP socket (bound to WithLocalAddr, if given) unless WithTransport is used,
+// in which case each packet is handed to the transport. With WithInterface,
+// IPv4 packets are sent from a socket bound to the interface's address, and
+// IPv6 ones with the interface as their zone. The error is only set if
+// nothing could be sent at all, for example when the socket can not be
+// opened.
+func WakeAll(ctx context.Context, targets []Target, opts ...Option) ([]Result, error) {
+	o := newOptions(opts)
+
+	send := func(ctx context.Context, mp *MagicPacket, t Target) error {
+		return o.transport.Send(ctx, mp)
+	}
+	if o.transport != nil {
+		for _, t := range targets {
+			if t.Addr != "" {
+				return nil, fmt.Errorf("target %s has an address, which can not be used with WithTransport", t.MAC)
+			}
+		}
+	} else {
+		// The local address must only be set when we have one, a nil
+		// *UDPAddr is not a nil Addr.
+		laddr := ""
+		if o.localAddr != nil {
+			laddr = o.localAddr.String()
+		}
+		pc, err := listenPacket(ctx, laddr)
+		if err != nil {
+			return nil, err
+		}
+		defer pc.Close()
+
+		// IPv4 packets leave the interface from a second socket bound to
+		// its address. If it has none, only IPv6 targets can be woken.
+		var pc4 net.PacketConn
+		var err4 error
+		if o.iface != "" && o.localAddr == nil {
+			var ifAddr *net.UDPAddr
+			if ifAddr, err4 = interfaceAddr(o.iface); err4 == nil {
+				if pc4, err4 = listenPacket(ctx, ifAddr.String()); err4 == nil {
+					defer pc4.Close()
+				}
+			}
+		}
+
+		send = func(ctx context.Context, mp *MagicPacket, t Target) error {
+			addr := t.Addr
+			if addr == "" {
+				addr = net.IPv4bcast.String()
+			}
+			udpAddr, err := resolve(ctx, o.resolver, withPort(addr, o.port))
+			if err != nil {
+				return err
+			}
+
+			conn := pc
+			switch {
+			case o.iface == "" || o.localAddr != nil:
+			case udpAddr.IP.To4() != nil:
+				if err4 != nil {
+					return err4
+				}
+				conn = pc4
+			case udpAddr.Zone == "":
+				udpAddr.Zone = o.iface
+			}
+
+			bs, err := mp.Marshal()
+			if err != nil {
+				return err
+			}
+			n, err := conn.WriteTo(bs, udpAddr)
+			if err == nil && n != len(bs) {
+				err = fmt.Errorf("magic packet sent was %d bytes (expected %d bytes sent)", n, len(bs))
+			}
+			return err
+		}
+	}
+
+	results := make([]Result, len(targets))
+	var wg sync.WaitGroup
+	for idx, target := range targets {
+		results[idx].Target = target
+		wg.Add(1)
+		go func(r *Result) {
+			defer wg.Done()
+			mp, err := New(r.Target.MAC, opts...)
+			if err == nil {
+				err = ctx.Err()
+			}
+			if err == nil {
+				err = send(ctx, mp, r.Target)
+			}
+			r.Err = err
+		}(&results[idx])
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// resolve looks up a "host:port" (or just host) UDP address, preferring IPv4.
+func resolve(ctx context.Context, resolver *net.Resolver, addr string) (*net.UDPAddr, error) {
+	host, port, err := net.SplitHostPort(withPort(addr, DefaultPort))
+	if err != nil {
+		return nil, err
+	}
+	portNum, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port (%s): %v", port, err)
+	}
+
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	ips, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range ips {
+		if ip.IP.To4() != nil {
+			return &net.UDPAddr{IP: ip.IP, Port: portNum}, nil
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no addresses found for host (%s)", host)
+	}
+	return &net.UDPAddr{IP: ips[0].IP, Port: portNum, Zone: ips[0].Zone}, nil
+}
+
+// listenPacket opens a UDP socket on `laddr`, which writes give up on once the
+// deadline of `ctx` passes.
+func listenPacket(ctx context.Context, laddr string) (net.PacketConn, error) {
+	var lc net.ListenConfig
+	pc, err := lc.ListenPacket(ctx, "udp", laddr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		pc.SetDeadline(deadline)
+	}
+	return pc, nil
+}
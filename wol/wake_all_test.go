@@ -0,0 +1,93 @@
+package wol
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestWakeAll(t *testing.T) {
+	a, b := listen(t), listen(t)
+	defer a.Close()
+	defer b.Close()
+
+	targets := []Target{
+		{MAC: "00:11:22:33:44:55", Addr: a.LocalAddr().String()},
+		{MAC: "not a mac", Addr: a.LocalAddr().String()},
+		{MAC: "00:11:22:33:44:66", Addr: b.LocalAddr().String()},
+		{MAC: "00:11:22:33:44:77", Addr: "127.0.0.1:port"},
+	}
+	results, err := WakeAll(context.Background(), targets)
+	assert.Nil(t, err)
+	if assert.Equal(t, len(targets), len(results)) {
+		for idx, result := range results {
+			assert.Equal(t, targets[idx], result.Target)
+		}
+		assert.Nil(t, results[0].Err)
+		assert.NotNil(t, results[1].Err)
+		assert.Nil(t, results[2].Err)
+		assert.NotNil(t, results[3].Err)
+	}
+
+	assert.Equal(t, "00:11:22:33:44:55", receive(t, a).MAC().String())
+	assert.Equal(t, "00:11:22:33:44:66", receive(t, b).MAC().String())
+}
+
+func TestWakeAllTransport(t *testing.T) {
+	r := &recorder{}
+	results, err := WakeAll(context.Background(), []Target{{MAC: "00:11:22:33:44:55"}}, WithTransport(r))
+	assert.Nil(t, err)
+	assert.Nil(t, results[0].Err)
+	assert.Equal(t, 1, len(r.sent))
+
+	// Nothing is sent once the context is cancelled.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err = WakeAll(ctx, []Target{{MAC: "00:11:22:33:44:55"}}, WithTransport(r))
+	assert.Nil(t, err)
+	assert.ErrorIs(t, results[0].Err, context.Canceled)
+	assert.Equal(t, 1, len(r.sent))
+}
+
+// Targets can not have their own addresses with a transport, since it has its
+// own destination.
+func TestWakeAllTransportAddr(t *testing.T) {
+	r := &recorder{}
+	_, err := WakeAll(context.Background(), []Target{
+		{MAC: "00:11:22:33:44:55"},
+		{MAC: "00:11:22:33:44:66", Addr: "10.0.0.255"},
+	}, WithTransport(r))
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, len(r.sent))
+}
+
+// With an interface, both IPv4 and IPv6 targets can be woken in one batch.
+func TestWakeAllInterface(t *testing.T) {
+	a := listen(t)
+	defer a.Close()
+	lo := loopbackInterface(t)
+	b, err := net.ListenUDP("udp6", &net.UDPAddr{IP: net.IPv6loopback})
+	if err != nil {
+		t.Skip("no IPv6 loopback:", err)
+	}
+	defer b.Close()
+	b.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	results, err := WakeAll(context.Background(), []Target{
+		{MAC: "00:11:22:33:44:55", Addr: a.LocalAddr().String()},
+		{MAC: "00:11:22:33:44:66", Addr: b.LocalAddr().String()},
+	}, WithInterface(lo))
+	assert.Nil(t, err)
+	assert.Nil(t, results[0].Err)
+	assert.Nil(t, results[1].Err)
+
+	assert.Equal(t, "00:11:22:33:44:55", receive(t, a).MAC().String())
+	assert.Equal(t, "00:11:22:33:44:66", receive(t, b).MAC().String())
+}
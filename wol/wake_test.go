@@ -0,0 +1,141 @@
+package wol
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// listen returns a local UDP socket which magic packets can be sent to.
+func listen(t *testing.T) *net.UDPConn {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Skip("no loopback:", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+// receive reads one magic packet from `conn`.
+func receive(t *testing.T, conn *net.UDPConn) *MagicPacket {
+	buf := make([]byte, 1500)
+	n, err := conn.Read(buf)
+	assert.Nil(t, err)
+	mp, err := Unmarshal(buf[:n])
+	assert.Nil(t, err)
+	return mp
+}
+
+func TestWake(t *testing.T) {
+	conn := listen(t)
+	defer conn.Close()
+
+	assert.Nil(t, Wake("00:11:22:33:44:55", conn.LocalAddr().String()))
+	mp := receive(t, conn)
+	assert.Equal(t, "00:11:22:33:44:55", mp.MAC().String())
+	assert.Nil(t, mp.Password())
+
+	assert.Nil(t, Wake("00:11:22:33:44:55", conn.LocalAddr().String(),
+		WithPassword("01:02:03:04:05:06"),
+		WithLocalAddr(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})))
+	mp = receive(t, conn)
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6}, mp.Password())
+}
+
+func TestWakeNegative(t *testing.T) {
+	assert.NotNil(t, Wake("not a mac", "127.0.0.1"))
+	assert.NotNil(t, Wake("00:11:22:33:44:55", "127.0.0.1", WithPassword("bad")))
+}
+
+func TestWithPort(t *testing.T) {
+	assert.Equal(t, "255.255.255.255:9", withPort("255.255.255.255", 0))
+	assert.Equal(t, "255.255.255.255:7", withPort("255.255.255.255", 7))
+	assert.Equal(t, "10.0.0.255:7", withPort("10.0.0.255:7", 0))
+	assert.Equal(t, "10.0.0.255:7", withPort("10.0.0.255:7", 9))
+	assert.Equal(t, "[ff02::1]:9", withPort("ff02::1", 0))
+	assert.Equal(t, "host.lan:9", withPort("host.lan", DefaultPort))
+}
+
+func TestWakePortAndInterface(t *testing.T) {
+	conn := listen(t)
+	defer conn.Close()
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+
+	// The port comes from WithPort when the destination has none.
+	assert.Nil(t, Wake("00:11:22:33:44:55", "127.0.0.1", WithPort(port)))
+	assert.Equal(t, "00:11:22:33:44:55", receive(t, conn).MAC().String())
+
+	// Or from the packet, when it was built with WithPort.
+	mp, err := New("00:11:22:33:44:66", WithPort(port))
+	assert.Nil(t, err)
+	assert.Nil(t, mp.SendContext(context.Background(), "127.0.0.1"))
+	assert.Equal(t, "00:11:22:33:44:66", receive(t, conn).MAC().String())
+
+	// Sending on the loopback interface uses its address.
+	lo := loopbackInterface(t)
+	assert.Nil(t, Wake("00:11:22:33:44:77", conn.LocalAddr().String(), WithInterface(lo)))
+	assert.Equal(t, "00:11:22:33:44:77", receive(t, conn).MAC().String())
+
+	assert.NotNil(t, Wake("00:11:22:33:44:55", "127.0.0.1", WithInterface("no-such-interface")))
+}
+
+// loopbackInterface returns the name of an up loopback interface with an IPv4
+// address.
+func loopbackInterface(t *testing.T) string {
+	ifaces, err := net.Interfaces()
+	assert.Nil(t, err)
+	for _, ifi := range ifaces {
+		if ifi.Flags&net.FlagLoopback == 0 || ifi.Flags&net.FlagUp == 0 {
+			continue
+		}
+		if _, err := interfaceAddr(ifi.Name); err == nil {
+			return ifi.Name
+		}
+	}
+	t.Skip("no IPv4 loopback interface")
+	return ""
+}
+
+func TestSendContext(t *testing.T) {
+	conn := listen(t)
+	defer conn.Close()
+
+	mp, err := NewWithPassword("00:11:22:33:44:55", "01:02:03:04:05:06")
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Nil(t, mp.SendContext(ctx, conn.LocalAddr().String()))
+	assert.Equal(t, mp, receive(t, conn))
+
+	assert.Nil(t, WakeContext(ctx, "00:11:22:33:44:66", conn.LocalAddr().String()))
+	assert.Equal(t, "00:11:22:33:44:66", receive(t, conn).MAC().String())
+
+	// Nothing is sent (or resolved) once the context is cancelled.
+	cancel()
+	err = WakeContext(ctx, "00:11:22:33:44:55", conn.LocalAddr().String())
+	assert.ErrorIs(t, err, context.Canceled)
+	err = WakeContext(ctx, "00:11:22:33:44:55", "wol.invalid:9")
+	assert.NotNil(t, err)
+}
+
+func TestWakeIPv6(t *testing.T) {
+	conn, err := net.ListenUDP("udp6", &net.UDPAddr{IP: net.IPv6loopback})
+	if err != nil {
+		t.Skip("no IPv6 loopback:", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+	assert.Nil(t, Wake("00:11:22:33:44:55", net.JoinHostPort("::1", strconv.Itoa(port))))
+	assert.Equal(t, "00:11:22:33:44:55", receive(t, conn).MAC().String())
+}